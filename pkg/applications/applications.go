@@ -2,21 +2,32 @@ package applications
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient"
 
+	"github.com/jenkins-x/jx-helpers/v3/pkg/kube"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/kube/jxenv"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
 
 	v1 "github.com/jenkins-x/jx-api/v4/pkg/apis/jenkins.io/v1"
 	jxc "github.com/jenkins-x/jx-api/v4/pkg/client/clientset/versioned"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/kube/naming"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/kube/services"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/stringhelpers"
 
 	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 )
 
@@ -41,56 +52,81 @@ func (l *List) Environments() map[string]v1.Environment {
 	return envs
 }
 
+// OrderedEnvironments returns all the unique environments of the list sorted by their promotion order then name
+func (l *List) OrderedEnvironments() []v1.Environment {
+	envMap := l.Environments()
+	answer := make([]v1.Environment, 0, len(envMap))
+	for k := range envMap {
+		answer = append(answer, envMap[k])
+	}
+	sort.Slice(answer, func(i, j int) bool {
+		if answer[i].Spec.Order != answer[j].Spec.Order {
+			return answer[i].Spec.Order < answer[j].Spec.Order
+		}
+		return answer[i].Name < answer[j].Name
+	})
+	return answer
+}
+
+// Find returns the application with the given name, falling back to the name of the SourceRepository or the git
+// repository of applications which are not part of a monorepo. Returns nil if there is no such application
+func (l *List) Find(name string) *Application {
+	for i := range l.Items {
+		if l.Items[i].Name() == name {
+			return &l.Items[i]
+		}
+	}
+	for i := range l.Items {
+		a := &l.Items[i]
+		if a.AppName == "" && (a.SourceRepository.Name == name || a.SourceRepository.Spec.Repo == name) {
+			return a
+		}
+	}
+	return nil
+}
+
 // Name returns the application name
 func (a *Application) Name() string {
+	if a.AppName != "" {
+		return a.AppName
+	}
 	return naming.ToValidName(a.SourceRepository.Spec.Repo)
 }
 
+// ApplicationNames returns the names of the applications declared by the ApplicationsAnnotation of a monorepo or
+// nil if the repository produces a single application named after the repository
+func ApplicationNames(sr *v1.SourceRepository) []string {
+	var answer []string
+	for _, name := range strings.Split(sr.Annotations[ApplicationsAnnotation], ",") {
+		name = naming.ToValidName(strings.TrimSpace(name))
+		if name != "" && stringhelpers.StringArrayIndex(answer, name) < 0 {
+			answer = append(answer, name)
+		}
+	}
+	return answer
+}
+
 // getVersion returns the version from the labels on the deployment if it can be deduced
 func getVersion(r *metav1.ObjectMeta) string {
-	if r != nil {
-		labels := r.Labels
-		if labels != nil {
-			v := labels["version"]
-			if v != "" {
-				return v
-			}
-			v = labels["chart"]
-			if v != "" {
-				arr := strings.Split(v, "-")
-				last := arr[len(arr)-1]
-				if last != "" {
-					return last
-				}
-				return v
-			}
+	if r == nil {
+		return ""
+	}
+	v, _ := versionFromLabels(r.Labels)
+	return v
+}
 
-			// find the kserve revision
-			kversion := labels[RevisionLabel]
-			if kversion != "" {
-				idx := strings.LastIndex(kversion, "-")
-				if idx > 0 {
-					kversion = kversion[idx+1:]
-				}
-				return kversion
-			}
-		}
+// revisionVersion returns the generation suffix of a knative revision name such as 00002 for myapp-00002
+func revisionVersion(revision string) string {
+	idx := strings.LastIndex(revision, "-")
+	if idx > 0 {
+		return revision[idx+1:]
 	}
-	return ""
+	return revision
 }
 
 // Pods returns the ratio of pods that are ready/replicas
 func Pods(d *appsv1.Deployment) string {
-	pods := ""
-	ready := d.Status.ReadyReplicas
-
-	if d.Spec.Replicas != nil && ready > 0 {
-		replicas := int32ToA(*d.Spec.Replicas)
-		strconv.FormatInt(int64(*d.Spec.Replicas), 10)
-		pods = int32ToA(ready) + "/" + replicas
-	}
-
-	return pods
+	return readyPods(d.Status.ReadyReplicas, d.Spec.Replicas)
 }
 
 func int32ToA(n int32) string {
@@ -103,124 +139,403 @@ func DeploymentURL(kc kubernetes.Interface, d *appsv1.Deployment, appName string
 	return url
 }
 
+// WorkloadURL returns the URL of an application in a namespace using services.FindServiceURL unless the context is
+// already done. Serverless workloads take their URL from their status instead
+func WorkloadURL(ctx context.Context, kc kubernetes.Interface, ns, appName string) string {
+	if ctx.Err() != nil {
+		return ""
+	}
+	url, _ := services.FindServiceURL(kc, ns, appName)
+	return url
+}
+
+// GetOptions the options for fetching applications
+type GetOptions struct {
+	JXClient   jxc.Interface
+	KubeClient kubernetes.Interface
+
+	// DynamicClient if not nil is used to discover Knative Services and KServe InferenceServices
+	DynamicClient dynamic.Interface
+
+	// ProgressiveDelivery the progressive delivery backends. If nil the DefaultProgressiveDeliveryBackends are used
+	ProgressiveDelivery []ProgressiveDeliveryBackend
+
+	// MatchStrategy how workloads are matched to applications. If blank MatchStrategyAuto is used
+	MatchStrategy MatchStrategy
+
+	// GitClient the git client used to fetch remote environments. If nil a git client bound to the context is
+	// created so that git commands are killed when the query is cancelled or times out
+	GitClient gitclient.Interface
+
+	// Namespace the development namespace containing the SourceRepositories and Environments
+	Namespace string
+
+	// Environments if not empty only the environments with these names are fetched
+	Environments []string
+
+	// RepositorySelector an optional label selector used to filter the SourceRepositories
+	RepositorySelector string
+
+	// DeploymentSelector an optional label selector used to filter the Deployments in each environment
+	DeploymentSelector string
+
+	// Timeout if non zero bounds the whole query
+	Timeout time.Duration
+
+	// RemoteTimeout if non zero bounds fetching each remote environment git repository
+	RemoteTimeout time.Duration
+
+	// SkipRemote disables fetching the git repositories of remote environments
+	SkipRemote bool
+
+	// RemoteCache if not nil reuses the clones of remote environment git repositories between invocations
+	RemoteCache *RemoteCache
+
+	// ReleaseFetcher fetches the release reports of remote environments. Defaults to cloning the git repository
+	ReleaseFetcher ReleaseFetcher
+
+	// Parallelism the maximum number of environments fetched concurrently. Defaults to DefaultParallelism
+	Parallelism int
+
+	// Strict returns an error if any environment cannot be fetched rather than returning partial results
+	Strict bool
+}
+
+// LazyCreateDynamicClient creates the dynamic client if it is nil. The dynamic client is only used to discover optional
+// custom resources such as Knative Services and Rollouts so if it cannot be created nil is returned
+func LazyCreateDynamicClient(client dynamic.Interface) dynamic.Interface {
+	client, err := kube.LazyCreateDynamicClient(client)
+	if err != nil {
+		log.Logger().Debugf("not discovering custom resources as the dynamic client could not be created: %s", err.Error())
+		return nil
+	}
+	return client
+}
+
+// DefaultParallelism the default number of environments fetched concurrently
+const DefaultParallelism = 4
+
 // GetApplications fetches all Applications
 func GetApplications(jxClient jxc.Interface, kubeClient kubernetes.Interface, namespace string, g gitclient.Interface) (List, error) {
+	return GetApplicationsWithOptions(context.TODO(), &GetOptions{
+		JXClient:   jxClient,
+		KubeClient: kubeClient,
+		Namespace:  namespace,
+		GitClient:  g,
+		Strict:     true,
+	})
+}
+
+// GetApplicationsWithOptions fetches the Applications using the given options. The context is used for all
+// kubernetes and git calls so that the query can be cancelled or bounded.
+//
+// Environments are fetched concurrently. If any environment cannot be fetched the applications are still returned
+// using the other environments. The failed environments are then listed in the Failures of the list, and added to each
+// application deployed in another environment, with their FetchStatus and FetchError set unless Strict is enabled in
+// which case an error combining the failures of every environment is returned
+func GetApplicationsWithOptions(ctx context.Context, o *GetOptions) (List, error) {
 	list := List{
 		Items: make([]Application, 0),
 	}
+	if o.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.Timeout)
+		defer cancel()
+	}
+	namespace := o.Namespace
 
 	// fetch ALL repositories
-	srList, err := jxClient.JenkinsV1().SourceRepositories(namespace).List(context.TODO(), metav1.ListOptions{})
+	srList, err := o.JXClient.JenkinsV1().SourceRepositories(namespace).List(ctx, metav1.ListOptions{LabelSelector: o.RepositorySelector})
 	if err != nil {
 		return list, fmt.Errorf("failed to find any SourceRepositories in namespace %s: %w", namespace, err)
 	}
 
 	// fetch all environments
-	envMap, _, err := jxenv.GetOrderedEnvironments(jxClient, namespace)
+	envMap, err := getEnvironments(ctx, o.JXClient, namespace)
 	if err != nil {
 		return list, fmt.Errorf("failed to fetch environments in namespace %s: %w", namespace, err)
 	}
+	permanentEnvsMap := PermanentEnvironments(envMap)
 
-	// only keep permanent environments
-	permanentEnvsMap := map[string]*v1.Environment{}
-	for _, env := range envMap {
-		if env.Spec.Kind.IsPermanent() {
-			permanentEnvsMap[env.Spec.Namespace] = env
+	// fetch deployments by environment (excluding dev)
+	var envs []*v1.Environment
+	for _, env := range permanentEnvsMap {
+		if env.Spec.Kind == v1.EnvironmentKindTypeDevelopment || !o.includesEnvironment(env) {
+			continue
 		}
+		if env.Spec.RemoteCluster && o.SkipRemote {
+			continue
+		}
+		envs = append(envs, env)
 	}
+	sortEnvironments(envs)
 
-	// copy repositories that aren't environments to our applications list
-	for i := range srList.Items {
-		srCopy := srList.Items[i]
-		if !jxenv.IsIncludedInTheGivenEnvs(permanentEnvsMap, &srCopy) {
-			list.Items = append(list.Items, Application{&srCopy, make(map[string]Environment)})
-		}
+	deployments, failures, err := o.fetchDeployments(ctx, envs)
+	if err != nil && o.Strict {
+		return list, err
 	}
+	list = NewListWithMatchStrategy(srList.Items, permanentEnvsMap, deployments, o.matchStrategy())
+	list.setEnvironmentFailures(permanentEnvsMap, failures)
+	return list, nil
+}
 
-	// fetch deployments by environment (excluding dev)
-	deployments := make(map[string]map[string]Deployment)
-	for _, env := range permanentEnvsMap {
+func (o *GetOptions) matchStrategy() MatchStrategy {
+	if o.MatchStrategy == "" {
+		return MatchStrategyAuto
+	}
+	return o.MatchStrategy
+}
 
-		if env.Spec.Kind == v1.EnvironmentKindTypeDevelopment {
-			continue
-		}
-		var envDeployments map[string]Deployment
-		if env.Spec.RemoteCluster {
-			envDeployments, err = GetRemoteDeployments(g, env)
-			if err != nil {
-				return list, err
+// fetchDeployments fetches the deployments of each environment using a bounded pool of workers. The failures are
+// returned indexed by environment namespace along with an error combining them in the order of the environments
+func (o *GetOptions) fetchDeployments(ctx context.Context, envs []*v1.Environment) (map[string]map[string]Deployment, map[string]error, error) {
+	deployments := make([]map[string]Deployment, len(envs))
+	errs := make([]error, len(envs))
+
+	parallelism := o.Parallelism
+	if parallelism <= 0 {
+		parallelism = DefaultParallelism
+	}
+	parallelism = min(parallelism, len(envs))
+
+	indexes := make(chan int)
+	wg := sync.WaitGroup{}
+	for w := 0; w < parallelism; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				deployments[i], errs[i] = o.fetchEnvironmentDeployments(ctx, envs[i])
 			}
-			deployments[env.Spec.Namespace] = envDeployments
+		}()
+	}
+	for i := range envs {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	answer := make(map[string]map[string]Deployment)
+	failures := map[string]error{}
+	var joined []error
+	for i, env := range envs {
+		if errs[i] != nil {
+			failures[env.Spec.Namespace] = errs[i]
+			joined = append(joined, fmt.Errorf("environment %s: %w", env.Name, errs[i]))
 			continue
 		}
+		answer[env.Spec.Namespace] = deployments[i]
+	}
+	return answer, failures, errors.Join(joined...)
+}
 
-		envDeployments, err = getDeployments(kubeClient, env.Spec.Namespace, env)
-		if err != nil {
-			return list, err
+// setEnvironmentFailures records the environments which could not be fetched, indexed by namespace, with the reason
+// so that they can be reported rather than silently omitted. The failed environments are only added to the
+// applications which are deployed in another environment so that undeployed applications are not shown because of them
+func (l *List) setEnvironmentFailures(envs map[string]*v1.Environment, failures map[string]error) {
+	deployed := make([]bool, len(l.Items))
+	for i := range l.Items {
+		deployed[i] = len(l.Items[i].Environments) > 0
+	}
+	for ns, err := range failures {
+		env := envs[ns]
+		if env == nil || err == nil {
+			continue
+		}
+		failure := Environment{
+			Environment: *env,
+			FetchStatus: EnvironmentStatusFor(err),
+			FetchError:  err.Error(),
+		}
+		l.Failures = append(l.Failures, failure)
+		for i := range l.Items {
+			if deployed[i] {
+				l.Items[i].Environments[env.Name] = failure
+			}
 		}
-		deployments[env.Spec.Namespace] = envDeployments
 	}
-
-	list.appendMatchingDeployments(permanentEnvsMap, deployments)
-	return list, nil
+	sort.Slice(l.Failures, func(i, j int) bool {
+		ei, ej := &l.Failures[i].Environment, &l.Failures[j].Environment
+		if ei.Spec.Order != ej.Spec.Order {
+			return ei.Spec.Order < ej.Spec.Order
+		}
+		return ei.Name < ej.Name
+	})
 }
 
-func getDeploymentAppNameInEnvironment(d *appsv1.Deployment, e *v1.Environment) (string, error) {
-	labels, err := metav1.LabelSelectorAsMap(d.Spec.Selector)
-	if err != nil {
-		return "", err
+// EnvironmentStatusFor returns the environment status for the error fetching it
+func EnvironmentStatusFor(err error) string {
+	if apierrors.IsForbidden(err) || apierrors.IsUnauthorized(err) {
+		return EnvironmentStatusForbidden
 	}
+	return EnvironmentStatusError
+}
 
-	name := GetAppName(labels["app"], e.Spec.Namespace)
-	return name, nil
+// fetchEnvironmentDeployments fetches the deployments of a single environment
+func (o *GetOptions) fetchEnvironmentDeployments(ctx context.Context, env *v1.Environment) (map[string]Deployment, error) {
+	if env.Spec.RemoteCluster {
+		return o.getRemoteDeployments(ctx, env)
+	}
+	return getDeployments(ctx, o.KubeClient, o.DynamicClient, o.progressiveDeliveryBackends(), env.Spec.Namespace, env, o.DeploymentSelector)
 }
 
-func (l *List) appendMatchingDeployments(envs map[string]*v1.Environment, deps map[string]map[string]Deployment) {
-	for _, app := range l.Items {
-		for envName, env := range envs {
-			for i := range deps[envName] {
-				dep := deps[envName][i]
-				if dep.Name == app.Name() && !dep.Canary {
-					app.Environments[env.Name] = Environment{
-						*env,
-						[]Deployment{dep},
-					}
-				}
-			}
-		}
+// progressiveDeliveryBackends returns the configured progressive delivery backends or the defaults
+func (o *GetOptions) progressiveDeliveryBackends() []ProgressiveDeliveryBackend {
+	if o.ProgressiveDelivery != nil {
+		return o.ProgressiveDelivery
 	}
+	return DefaultProgressiveDeliveryBackends()
+}
 
+// sortEnvironments sorts the environments by their promotion order then name
+func sortEnvironments(envs []*v1.Environment) {
+	sort.Slice(envs, func(i, j int) bool {
+		if envs[i].Spec.Order != envs[j].Spec.Order {
+			return envs[i].Spec.Order < envs[j].Spec.Order
+		}
+		return envs[i].Name < envs[j].Name
+	})
 }
 
-func CreateDeployment(d *appsv1.Deployment, env *v1.Environment) (Deployment, error) {
-	name := GetAppName(d.Name, d.Namespace)
+// includesEnvironment returns true if the environment matches the environment filter
+func (o *GetOptions) includesEnvironment(env *v1.Environment) bool {
+	return len(o.Environments) == 0 || stringhelpers.StringArrayIndex(o.Environments, env.Name) >= 0
+}
 
-	answer := Deployment{
-		Name:    name,
-		Pods:    Pods(d),
-		Version: getVersion(&d.ObjectMeta),
-		Canary:  isCanaryAuxiliaryDeployment(d),
+// getRemoteDeployments fetches the deployments of a remote environment bounded by the remote timeout
+func (o *GetOptions) getRemoteDeployments(ctx context.Context, env *v1.Environment) (map[string]Deployment, error) {
+	if o.RemoteTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.RemoteTimeout)
+		defer cancel()
 	}
-	depAppName, err := getDeploymentAppNameInEnvironment(d, env)
+	fetcher := o.ReleaseFetcher
+	if fetcher == nil {
+		fetcher = &CloneReleaseFetcher{GitClient: o.GitClient, Cache: o.RemoteCache}
+	}
+	answer, err := GetRemoteDeploymentsWithFetcher(ctx, fetcher, env)
+	if err != nil && ctx.Err() != nil {
+		return answer, fmt.Errorf("failed to fetch remote environment %s: %w", env.Name, ctx.Err())
+	}
+	return answer, err
+}
+
+// getEnvironments returns the environments in the namespace indexed by name
+func getEnvironments(ctx context.Context, jxClient jxc.Interface, ns string) (map[string]*v1.Environment, error) {
+	m := map[string]*v1.Environment{}
+	envs, err := jxClient.JenkinsV1().Environments(ns).List(ctx, metav1.ListOptions{})
 	if err != nil {
-		return answer, fmt.Errorf("getting app name: %w", err)
+		return m, err
 	}
-	if depAppName != "" {
-		answer.Name = depAppName
+	for i := range envs.Items {
+		env := &envs.Items[i]
+		m[env.Name] = env
 	}
-	return answer, nil
+	return m, nil
+}
+
+// PermanentEnvironments returns the permanent environments indexed by their namespace
+func PermanentEnvironments(envMap map[string]*v1.Environment) map[string]*v1.Environment {
+	permanentEnvsMap := map[string]*v1.Environment{}
+	for _, env := range envMap {
+		if env.Spec.Kind.IsPermanent() {
+			permanentEnvsMap[env.Spec.Namespace] = env
+		}
+	}
+	return permanentEnvsMap
+}
 
+// NewList creates the applications from the source repositories, the permanent environments indexed by namespace
+// and the deployments indexed by namespace
+func NewList(repositories []v1.SourceRepository, permanentEnvsMap map[string]*v1.Environment, deployments map[string]map[string]Deployment) List {
+	return NewListWithMatchStrategy(repositories, permanentEnvsMap, deployments, MatchStrategyAuto)
 }
 
-// IsCanaryAuxiliaryDeployment returns whether this deployment has been created automatically by Flagger from a Canary object
-func isCanaryAuxiliaryDeployment(d *appsv1.Deployment) bool {
-	ownerReferences := d.GetObjectMeta().GetOwnerReferences()
-	for i := range ownerReferences {
-		if ownerReferences[i].Kind == "Canary" {
-			return true
+// NewListWithMatchStrategy creates the applications matching the deployments to them with the given strategy
+func NewListWithMatchStrategy(repositories []v1.SourceRepository, permanentEnvsMap map[string]*v1.Environment, deployments map[string]map[string]Deployment, strategy MatchStrategy) List {
+	list := List{
+		Items: make([]Application, 0),
+	}
+
+	// copy repositories that aren't environments to our applications list
+	for i := range repositories {
+		srCopy := repositories[i]
+		if !jxenv.IsIncludedInTheGivenEnvs(permanentEnvsMap, &srCopy) {
+			names := ApplicationNames(&srCopy)
+			if len(names) == 0 {
+				list.Items = append(list.Items, Application{SourceRepository: &srCopy, Environments: make(map[string]Environment)})
+				continue
+			}
+			// a monorepo produces an application per name which are kept together under the repository
+			for _, name := range names {
+				list.Items = append(list.Items, Application{SourceRepository: &srCopy, Environments: make(map[string]Environment), AppName: name})
+			}
 		}
 	}
-	return false
+
+	list.appendMatchingDeployments(permanentEnvsMap, deployments, strategy)
+	return list
+}
+
+// appendMatchingDeployments adds every deployment of each application to the environment it is in, ordered by key
+// so that an application with several workloads in the same namespace is shown consistently. Each workload is added
+// to at most one application preferring the applications it strongly matches then those named after it
+func (l *List) appendMatchingDeployments(envs map[string]*v1.Environment, deps map[string]map[string]Deployment, strategy MatchStrategy) {
+	for envName, env := range envs {
+		envDeps := deps[envName]
+		keys := make([]string, 0, len(envDeps))
+		for k := range envDeps {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		matches := make([][]Deployment, len(l.Items))
+		assigned := map[string]bool{}
+		assign := func(kind matchKind, include func(i int) bool) {
+			for _, k := range keys {
+				dep := envDeps[k]
+				if dep.Canary || assigned[k] {
+					continue
+				}
+				for i := range l.Items {
+					app := &l.Items[i]
+					if include(i) && strategy.match(app, &dep) == kind {
+						// the workload may have been matched by its metadata so lets show it with the application name
+						dep.Name = app.Name()
+						matches[i] = append(matches[i], dep)
+						assigned[k] = true
+						break
+					}
+				}
+			}
+		}
+		assign(matchStrong, func(int) bool { return true })
+		assign(matchName, func(int) bool { return true })
+		strong := make([]bool, len(l.Items))
+		for i := range matches {
+			strong[i] = len(matches[i]) > 0
+		}
+		assign(matchWeak, func(i int) bool { return !strong[i] })
+
+		for i := range l.Items {
+			if len(matches[i]) > 0 {
+				l.Items[i].Environments[env.Name] = Environment{
+					Environment: *env,
+					Deployments: matches[i],
+				}
+			}
+		}
+	}
+}
+
+// CreateDeployment creates the application deployment of a Deployment in the environment
+func CreateDeployment(d *appsv1.Deployment, env *v1.Environment) (Deployment, error) {
+	w, err := NewDeploymentWorkload(d)
+	if err != nil {
+		return Deployment{}, fmt.Errorf("getting app name: %w", err)
+	}
+	w.Auxiliary = isAuxiliary(DefaultProgressiveDeliveryBackends(), w.ObjectMeta)
+	return CreateWorkloadDeployment(w, env), nil
 }
 
 func GetAppName(name string, namespaces ...string) string {
@@ -248,21 +563,35 @@ func GetAppName(name string, namespaces ...string) string {
 	return name
 }
 
-// getDeployments get deployments in the given namespace
-func getDeployments(kubeClient kubernetes.Interface, ns string, env *v1.Environment) (map[string]Deployment, error) {
+// getDeployments get the application deployments of the workloads in the given namespace
+func getDeployments(ctx context.Context, kubeClient kubernetes.Interface, dynamicClient dynamic.Interface, backends []ProgressiveDeliveryBackend, ns string, env *v1.Environment, selector string) (map[string]Deployment, error) {
 	answer := map[string]Deployment{}
-	deps, err := kubeClient.AppsV1().Deployments(ns).List(context.TODO(), metav1.ListOptions{})
+	workloads, err := getWorkloads(ctx, kubeClient, ns, selector)
+	if err != nil {
+		return answer, err
+	}
+	serverless, err := getServerlessWorkloads(ctx, dynamicClient, ns, selector)
+	if err != nil {
+		return answer, err
+	}
+	workloads = append(workloads, serverless...)
+	workloads, err = applyProgressiveDelivery(ctx, ClientReplicaSets(kubeClient), backends, workloads, func(resource schema.GroupVersionResource) ([]unstructured.Unstructured, error) {
+		return listOptionalResources(ctx, dynamicClient, resource, ns, selector)
+	})
+	if err != nil {
+		return answer, err
+	}
+	releases, err := getHelmReleases(ctx, kubeClient, ns)
 	if err != nil {
 		return answer, err
 	}
-	for i := range deps.Items {
-		d := &deps.Items[i]
-		deployment, err := CreateDeployment(d, env)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create Deployment for %s in namespace %s: %w", d.Name, ns, err)
+	workloads = applyHelmReleases(workloads, releases)
+	for _, w := range workloads {
+		deployment := CreateWorkloadDeployment(w, env)
+		if w.hasServiceURL() {
+			deployment.URL = WorkloadURL(ctx, kubeClient, ns, deployment.Name)
 		}
-		deployment.URL = DeploymentURL(kubeClient, d, deployment.Name)
-		answer[d.Name] = deployment
+		answer[workloadKey(w.Kind, w.ObjectMeta.Name)] = deployment
 	}
 	return answer, nil
 }
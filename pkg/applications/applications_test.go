@@ -1,12 +1,25 @@
 package applications
 
 import (
+	"context"
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
 
 	v1 "github.com/jenkins-x/jx-api/v4/pkg/apis/jenkins.io/v1"
+	fakejx "github.com/jenkins-x/jx-api/v4/pkg/client/clientset/versioned/fake"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/kube/services"
 )
 
 func TestAppendMatchingDeployments(t *testing.T) {
@@ -29,14 +42,14 @@ func TestAppendMatchingDeployments(t *testing.T) {
 		{
 			"Source repository doesn't have a matching deployment",
 			List{
-				[]Application{
+				Items: []Application{
 					{
-						&v1.SourceRepository{
+						SourceRepository: &v1.SourceRepository{
 							Spec: v1.SourceRepositorySpec{
 								Repo: "my-repo-name",
 							},
 						},
-						make(map[string]Environment),
+						Environments: make(map[string]Environment),
 					},
 				},
 			},
@@ -47,14 +60,14 @@ func TestAppendMatchingDeployments(t *testing.T) {
 		{
 			"Source repository matches a single deployment",
 			List{
-				[]Application{
+				Items: []Application{
 					{
-						&v1.SourceRepository{
+						SourceRepository: &v1.SourceRepository{
 							Spec: v1.SourceRepositorySpec{
 								Repo: "my-repo-name",
 							},
 						},
-						make(map[string]Environment),
+						Environments: make(map[string]Environment),
 					},
 				},
 			},
@@ -78,14 +91,14 @@ func TestAppendMatchingDeployments(t *testing.T) {
 		{
 			"Source repository matches multiple deployments",
 			List{
-				[]Application{
+				Items: []Application{
 					{
-						&v1.SourceRepository{
+						SourceRepository: &v1.SourceRepository{
 							Spec: v1.SourceRepositorySpec{
 								Repo: "my-repo-name",
 							},
 						},
-						make(map[string]Environment),
+						Environments: make(map[string]Environment),
 					},
 				},
 			},
@@ -123,10 +136,56 @@ func TestAppendMatchingDeployments(t *testing.T) {
 			},
 			1, 2, 1,
 		},
+		{
+			"Source repository matches multiple workloads in the same environment",
+			List{
+				Items: []Application{
+					{
+						SourceRepository: &v1.SourceRepository{
+							Spec: v1.SourceRepositorySpec{
+								Repo: "my-repo-name",
+							},
+						},
+						Environments: make(map[string]Environment),
+					},
+				},
+			},
+			map[string]*v1.Environment{
+				"staging": {
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "staging",
+					},
+					Spec: v1.EnvironmentSpec{
+						Namespace: "jx-staging",
+						Kind:      v1.EnvironmentKindTypePermanent,
+					},
+				},
+			},
+			map[string]map[string]Deployment{
+				"staging": {
+					"my-repo-name": Deployment{
+						Name: "my-repo-name",
+						Kind: KindDeployment,
+					},
+					"StatefulSet/my-repo-name": Deployment{
+						Name: "my-repo-name",
+						Kind: KindStatefulSet,
+					},
+					"my-repo-name-primary": Deployment{
+						Name:   "my-repo-name",
+						Canary: true,
+					},
+					"another-app": Deployment{
+						Name: "another-app",
+					},
+				},
+			},
+			1, 1, 2,
+		},
 	}
 
 	for _, test := range tests {
-		test.list.appendMatchingDeployments(test.environments, test.deployments)
+		test.list.appendMatchingDeployments(test.environments, test.deployments, MatchStrategyAuto)
 
 		assert.Equal(t, test.wantApplications, len(test.list.Items), test.name)
 
@@ -138,3 +197,249 @@ func TestAppendMatchingDeployments(t *testing.T) {
 		}
 	}
 }
+
+func TestAppendMatchingDeploymentsOrder(t *testing.T) {
+	env := &v1.Environment{
+		ObjectMeta: metav1.ObjectMeta{Name: "staging"},
+		Spec:       v1.EnvironmentSpec{Namespace: "jx-staging", Kind: v1.EnvironmentKindTypePermanent},
+	}
+	deps := map[string]map[string]Deployment{
+		"jx-staging": {
+			"myapp":             {Name: "myapp", Kind: KindDeployment},
+			"CronJob/myapp":     {Name: "myapp", Kind: KindCronJob},
+			"StatefulSet/myapp": {Name: "myapp", Kind: KindStatefulSet},
+		},
+	}
+
+	for i := 0; i < 5; i++ {
+		list := List{Items: []Application{{SourceRepository: &v1.SourceRepository{Spec: v1.SourceRepositorySpec{Repo: "myapp"}}, Environments: map[string]Environment{}}}}
+		list.appendMatchingDeployments(map[string]*v1.Environment{"jx-staging": env}, deps, MatchStrategyAuto)
+
+		var kinds []string
+		for _, d := range list.Items[0].Environments["staging"].Deployments {
+			kinds = append(kinds, d.Kind)
+		}
+		assert.Equal(t, []string{KindCronJob, KindStatefulSet, KindDeployment}, kinds)
+	}
+}
+
+func TestNewListMonorepo(t *testing.T) {
+	env := &v1.Environment{
+		ObjectMeta: metav1.ObjectMeta{Name: "staging"},
+		Spec:       v1.EnvironmentSpec{Namespace: "jx-staging", Kind: v1.EnvironmentKindTypePermanent},
+	}
+	repositories := []v1.SourceRepository{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "myorg-monorepo",
+				Annotations: map[string]string{ApplicationsAnnotation: "api, worker,api,"},
+			},
+			Spec: v1.SourceRepositorySpec{Org: "myorg", Repo: "monorepo"},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "myorg-single"},
+			Spec:       v1.SourceRepositorySpec{Org: "myorg", Repo: "single"},
+		},
+	}
+	deps := map[string]map[string]Deployment{
+		"jx-staging": {
+			"api":      {Name: "api", Version: "1.0.0"},
+			"worker":   {Name: "worker", Version: "1.0.0"},
+			"monorepo": {Name: "monorepo", Version: "1.0.0"},
+			"single":   {Name: "single", Version: "2.0.0"},
+		},
+	}
+
+	list := NewList(repositories, map[string]*v1.Environment{"jx-staging": env}, deps)
+	require.Len(t, list.Items, 3)
+	var names []string
+	for i := range list.Items {
+		a := &list.Items[i]
+		names = append(names, a.Name())
+		require.Len(t, a.Environments["staging"].Deployments, 1, a.Name())
+		assert.Equal(t, a.Name(), a.Environments["staging"].Deployments[0].Name)
+	}
+	assert.Equal(t, []string{"api", "worker", "single"}, names)
+	assert.Equal(t, "monorepo", list.Items[1].Spec.Repo, "the applications should be grouped under the repository")
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.0.0", "1.0.0", 0},
+		{"1.0.10", "1.0.9", 1},
+		{"v1.2.0", "1.10.0", -1},
+		{"", "0.0.1", -1},
+		{"1.0.0", "", 1},
+		{"abc", "abd", -1},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.want, CompareVersions(test.a, test.b), "%s vs %s", test.a, test.b)
+	}
+}
+
+func TestGetApplicationsWithOptions(t *testing.T) {
+	ns := "jx"
+	jxClient := fakejx.NewSimpleClientset(
+		&v1.Environment{
+			ObjectMeta: metav1.ObjectMeta{Name: "staging", Namespace: ns},
+			Spec: v1.EnvironmentSpec{
+				Namespace: "jx-staging",
+				Kind:      v1.EnvironmentKindTypePermanent,
+			},
+		},
+		&v1.Environment{
+			ObjectMeta: metav1.ObjectMeta{Name: "production", Namespace: ns},
+			Spec: v1.EnvironmentSpec{
+				Namespace:     "jx-production",
+				Kind:          v1.EnvironmentKindTypePermanent,
+				RemoteCluster: true,
+			},
+		},
+		&v1.SourceRepository{
+			ObjectMeta: metav1.ObjectMeta{Name: "myorg-myapp", Namespace: ns, Labels: map[string]string{"owner": "myorg"}},
+			Spec:       v1.SourceRepositorySpec{Org: "myorg", Repo: "myapp"},
+		},
+		&v1.SourceRepository{
+			ObjectMeta: metav1.ObjectMeta{Name: "other-another", Namespace: ns, Labels: map[string]string{"owner": "other"}},
+			Spec:       v1.SourceRepositorySpec{Org: "other", Repo: "another"},
+		},
+	)
+	kubeClient := fake.NewSimpleClientset(
+		newTestDeployment("myapp", "jx-staging", "1.2.3"),
+		newTestDeployment("another", "jx-staging", "2.0.0"),
+	)
+
+	list, err := GetApplicationsWithOptions(context.TODO(), &GetOptions{
+		JXClient:           jxClient,
+		KubeClient:         kubeClient,
+		Namespace:          ns,
+		Environments:       []string{"staging"},
+		RepositorySelector: "owner=myorg",
+		SkipRemote:         true,
+	})
+	require.NoError(t, err)
+	require.Len(t, list.Items, 1, "should only find the selected repository")
+
+	app := list.Items[0]
+	assert.Equal(t, "myapp", app.Name())
+	require.Contains(t, app.Environments, "staging")
+	assert.NotContains(t, app.Environments, "production", "should not fetch the remote environment")
+	assert.Equal(t, "1.2.3", app.Environments["staging"].Deployments[0].Version)
+}
+
+func TestContextCommandRunnerCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := NewContextGitClient(ctx).Command("", "version")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestGetApplicationsEnvironmentFailures(t *testing.T) {
+	ns := "jx"
+	var objects []runtime.Object
+	for i, name := range []string{"production", "staging", "qa"} {
+		objects = append(objects, &v1.Environment{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ns},
+			Spec: v1.EnvironmentSpec{
+				Namespace: "jx-" + name,
+				Kind:      v1.EnvironmentKindTypePermanent,
+				Order:     int32(i),
+			},
+		})
+	}
+	objects = append(objects, &v1.SourceRepository{
+		ObjectMeta: metav1.ObjectMeta{Name: "myorg-myapp", Namespace: ns},
+		Spec:       v1.SourceRepositorySpec{Org: "myorg", Repo: "myapp"},
+	}, &v1.SourceRepository{
+		ObjectMeta: metav1.ObjectMeta{Name: "myorg-undeployed", Namespace: ns},
+		Spec:       v1.SourceRepositorySpec{Org: "myorg", Repo: "undeployed"},
+	})
+	jxClient := fakejx.NewSimpleClientset(objects...)
+
+	kubeClient := fake.NewSimpleClientset(newTestDeployment("myapp", "jx-staging", "1.2.3"))
+	kubeClient.PrependReactor("list", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		switch action.GetNamespace() {
+		case "jx-production":
+			return true, nil, apierrors.NewForbidden(schema.GroupResource{Group: "apps", Resource: "deployments"}, "", fmt.Errorf("no access"))
+		case "jx-qa":
+			return true, nil, fmt.Errorf("connection refused")
+		}
+		return false, nil, nil
+	})
+
+	o := &GetOptions{
+		JXClient:    jxClient,
+		KubeClient:  kubeClient,
+		Namespace:   ns,
+		Parallelism: 2,
+	}
+	list, err := GetApplicationsWithOptions(context.TODO(), o)
+	require.NoError(t, err, "should return partial results")
+	require.Len(t, list.Items, 2)
+	assert.Empty(t, list.Items[1].Environments, "should not add the failed environments to an application which is not deployed")
+	require.Len(t, list.Failures, 2)
+	assert.Equal(t, "production", list.Failures[0].Name)
+	assert.Equal(t, EnvironmentStatusForbidden, list.Failures[0].FetchStatus)
+	assert.Equal(t, "qa", list.Failures[1].Name)
+
+	envs := list.Items[0].Environments
+	assert.Equal(t, "1.2.3", envs["staging"].Deployments[0].Version, "should still return the successful environments")
+	assert.Empty(t, envs["staging"].FetchStatus)
+	assert.Equal(t, EnvironmentStatusForbidden, envs["production"].FetchStatus)
+	assert.Equal(t, EnvironmentStatusError, envs["qa"].FetchStatus)
+	assert.Equal(t, "connection refused", envs["qa"].FetchError)
+
+	o.Strict = true
+	_, err = GetApplicationsWithOptions(context.TODO(), o)
+	require.Error(t, err)
+	assert.Equal(t, "environment production: deployments.apps is forbidden: no access\nenvironment qa: connection refused", err.Error())
+}
+
+func TestWorkloadURL(t *testing.T) {
+	ns := "jx-staging"
+	kubeClient := fake.NewSimpleClientset(
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "exposed",
+				Namespace:   ns,
+				Annotations: map[string]string{services.ExposeURLAnnotation: "http://exposed.example.com"},
+			},
+		},
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "secure", Namespace: ns},
+		},
+		&networkingv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Name: "exposed", Namespace: ns},
+			Spec: networkingv1.IngressSpec{
+				Rules: []networkingv1.IngressRule{{Host: "ignored.example.com"}},
+			},
+		},
+		&networkingv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Name: "secure", Namespace: ns},
+			Spec: networkingv1.IngressSpec{
+				TLS:   []networkingv1.IngressTLS{{Hosts: []string{"secure.example.com"}}},
+				Rules: []networkingv1.IngressRule{{Host: "secure.example.com"}},
+			},
+		},
+	)
+
+	ctx := context.Background()
+	for _, name := range []string{"exposed", "secure"} {
+		want, err := services.FindServiceURL(kubeClient, ns, name)
+		require.NoError(t, err)
+		assert.Equal(t, want, WorkloadURL(ctx, kubeClient, ns, name), "URL of %s", name)
+		d := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ns}}
+		assert.Equal(t, want, DeploymentURL(kubeClient, d, name), "deployment URL of %s", name)
+	}
+	assert.Equal(t, "http://exposed.example.com", WorkloadURL(ctx, kubeClient, ns, "exposed"))
+	assert.Equal(t, "https://secure.example.com", WorkloadURL(ctx, kubeClient, ns, "secure"))
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.Empty(t, WorkloadURL(cancelled, kubeClient, ns, "exposed"))
+}
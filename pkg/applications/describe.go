@@ -0,0 +1,360 @@
+package applications
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// OutputDescriptionKind the kind of the machine readable description of an application
+	OutputDescriptionKind = "ApplicationDescription"
+
+	// DefaultDescribeEvents the default number of recent events shown for each workload
+	DefaultDescribeEvents = 10
+)
+
+// Description the details of a single application across its environments
+type Description struct {
+	APIVersion   string                `json:"apiVersion"`
+	Kind         string                `json:"kind"`
+	Name         string                `json:"name"`
+	Repository   DescribeRepository    `json:"repository"`
+	Environments []DescribeEnvironment `json:"environments"`
+}
+
+// DescribeRepository the details of the SourceRepository of an application
+type DescribeRepository struct {
+	Name     string `json:"name"`
+	Provider string `json:"provider,omitempty"`
+	Kind     string `json:"kind,omitempty"`
+	Owner    string `json:"owner,omitempty"`
+	Repo     string `json:"repo,omitempty"`
+	URL      string `json:"url,omitempty"`
+}
+
+// DescribeEnvironment the workloads of an application in an environment
+type DescribeEnvironment struct {
+	Name        string             `json:"name"`
+	Namespace   string             `json:"namespace,omitempty"`
+	Kind        string             `json:"kind,omitempty"`
+	Remote      bool               `json:"remote,omitempty"`
+	FetchStatus string             `json:"fetchStatus,omitempty"`
+	FetchError  string             `json:"fetchError,omitempty"`
+	Workloads   []DescribeWorkload `json:"workloads"`
+}
+
+// DescribeWorkload a workload of an application along with its pods, ingress hosts and recent events
+type DescribeWorkload struct {
+	Deployment
+	Restarts     int32           `json:"restarts"`
+	PodDetails   []DescribePod   `json:"podDetails,omitempty"`
+	IngressHosts []string        `json:"ingressHosts,omitempty"`
+	Events       []DescribeEvent `json:"events,omitempty"`
+}
+
+// DescribePod the status of a pod of a workload
+type DescribePod struct {
+	Name     string   `json:"name"`
+	Phase    string   `json:"phase,omitempty"`
+	Ready    string   `json:"ready"`
+	Restarts int32    `json:"restarts"`
+	Images   []string `json:"images,omitempty"`
+}
+
+// DescribeEvent a kubernetes event of a workload or one of its pods
+type DescribeEvent struct {
+	Type     string      `json:"type,omitempty"`
+	Reason   string      `json:"reason,omitempty"`
+	Object   string      `json:"object"`
+	Message  string      `json:"message,omitempty"`
+	Count    int32       `json:"count,omitempty"`
+	LastSeen metav1.Time `json:"lastSeen,omitempty"`
+}
+
+// DescribeOptions the clients and settings used to describe an application
+type DescribeOptions struct {
+	KubeClient    kubernetes.Interface
+	DynamicClient dynamic.Interface
+
+	// Events the maximum number of recent events of each workload
+	Events int
+}
+
+// Describe looks up the pods, ingress hosts and events of the workloads of the application in the given environments
+// which are in this cluster. Remote environments only include the deployments from their release report
+func (o *DescribeOptions) Describe(ctx context.Context, a *Application, envNames []string) (*Description, error) {
+	sr := a.SourceRepository
+	answer := &Description{
+		APIVersion: OutputAPIVersion,
+		Kind:       OutputDescriptionKind,
+		Name:       a.Name(),
+		Repository: DescribeRepository{
+			Name:     sr.Name,
+			Provider: sr.Spec.Provider,
+			Kind:     sr.Spec.ProviderKind,
+			Owner:    sr.Spec.Org,
+			Repo:     sr.Spec.Repo,
+			URL:      sr.Spec.URL,
+		},
+		Environments: []DescribeEnvironment{},
+	}
+	for _, name := range envNames {
+		env, ok := a.Environments[name]
+		if !ok {
+			continue
+		}
+		de := DescribeEnvironment{
+			Name:        name,
+			Namespace:   env.Spec.Namespace,
+			Kind:        string(env.Spec.Kind),
+			Remote:      env.Spec.RemoteCluster,
+			FetchStatus: env.FetchStatus,
+			FetchError:  env.FetchError,
+			Workloads:   []DescribeWorkload{},
+		}
+		var resources *namespaceResources
+		for i := range env.Deployments {
+			w := DescribeWorkload{Deployment: env.Deployments[i]}
+			if !de.Remote && de.FetchStatus == "" && w.Workload != "" {
+				var err error
+				if resources == nil {
+					resources, err = o.namespaceResources(ctx, env.Spec.Namespace)
+					if err != nil {
+						return answer, fmt.Errorf("failed to describe namespace %s: %w", env.Spec.Namespace, err)
+					}
+				}
+				err = o.describeWorkload(ctx, env.Spec.Namespace, resources, &w)
+				if err != nil {
+					return answer, fmt.Errorf("failed to describe %s %s in namespace %s: %w", w.Kind, w.Workload, env.Spec.Namespace, err)
+				}
+			}
+			de.Workloads = append(de.Workloads, w)
+		}
+		answer.Environments = append(answer.Environments, de)
+	}
+	return answer, nil
+}
+
+// namespaceResources the ingresses and events of a namespace which are listed once and shared by its workloads
+type namespaceResources struct {
+	ingresses []networkingv1.Ingress
+	events    []corev1.Event
+}
+
+// namespaceResources lists the ingresses of the namespace and its events if they are shown
+func (o *DescribeOptions) namespaceResources(ctx context.Context, ns string) (*namespaceResources, error) {
+	answer := &namespaceResources{}
+	ingresses, err := o.KubeClient.NetworkingV1().Ingresses(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		if !skipWorkloadError("Ingress", ns, err) {
+			return nil, err
+		}
+	} else {
+		answer.ingresses = ingresses.Items
+	}
+	if o.Events <= 0 {
+		return answer, nil
+	}
+	events, err := o.KubeClient.CoreV1().Events(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		if !skipWorkloadError("Event", ns, err) {
+			return nil, err
+		}
+	} else {
+		answer.events = events.Items
+	}
+	return answer, nil
+}
+
+// describeWorkload adds the pods, ingress hosts and recent events of the workload in the namespace
+func (o *DescribeOptions) describeWorkload(ctx context.Context, ns string, resources *namespaceResources, w *DescribeWorkload) error {
+	selector, err := o.podSelector(ctx, ns, w.Kind, w.Workload)
+	if err != nil {
+		return err
+	}
+	var podNames []string
+	if selector != nil && !selector.Empty() {
+		pods, err := o.KubeClient.CoreV1().Pods(ns).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+		if err != nil {
+			if !skipWorkloadError("Pod", ns, err) {
+				return err
+			}
+		} else {
+			for i := range pods.Items {
+				pod := newDescribePod(&pods.Items[i])
+				w.PodDetails = append(w.PodDetails, pod)
+				w.Restarts += pod.Restarts
+				podNames = append(podNames, pod.Name)
+			}
+		}
+	}
+
+	w.IngressHosts = ingressHosts(resources.ingresses, w.Name, w.Workload)
+	w.Events = o.events(resources.events, ns, w.Workload, podNames)
+	return nil
+}
+
+// podSelector returns the selector of the pods of the workload or nil if they cannot be found
+func (o *DescribeOptions) podSelector(ctx context.Context, ns, kind, name string) (labels.Selector, error) {
+	var selector *metav1.LabelSelector
+	switch kind {
+	case KindDeployment:
+		r, err := o.KubeClient.AppsV1().Deployments(ns).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, ignoreMissingWorkload(kind, ns, err)
+		}
+		selector = r.Spec.Selector
+	case KindStatefulSet:
+		r, err := o.KubeClient.AppsV1().StatefulSets(ns).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, ignoreMissingWorkload(kind, ns, err)
+		}
+		selector = r.Spec.Selector
+	case KindDaemonSet:
+		r, err := o.KubeClient.AppsV1().DaemonSets(ns).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, ignoreMissingWorkload(kind, ns, err)
+		}
+		selector = r.Spec.Selector
+	case KindKnativeService:
+		return labels.SelectorFromSet(labels.Set{"serving.knative.dev/service": name}), nil
+	case KindInferenceService:
+		return labels.SelectorFromSet(labels.Set{"serving.kserve.io/inferenceservice": name}), nil
+	case KindRollout:
+		if o.DynamicClient == nil {
+			return nil, nil
+		}
+		u, err := o.DynamicClient.Resource(RolloutResource).Namespace(ns).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, ignoreMissingWorkload(kind, ns, err)
+		}
+		matchLabels, _, _ := unstructured.NestedStringMap(u.Object, "spec", "selector", "matchLabels")
+		return labels.SelectorFromSet(matchLabels), nil
+	default:
+		return nil, nil
+	}
+	if selector == nil {
+		return nil, nil
+	}
+	return metav1.LabelSelectorAsSelector(selector)
+}
+
+// ignoreMissingWorkload returns nil if the workload has been removed or cannot be read
+func ignoreMissingWorkload(kind, ns string, err error) error {
+	if skipWorkloadError(kind, ns, err) {
+		return nil
+	}
+	return err
+}
+
+// newDescribePod returns the readiness and restarts of the pod
+func newDescribePod(pod *corev1.Pod) DescribePod {
+	answer := DescribePod{
+		Name:  pod.Name,
+		Phase: string(pod.Status.Phase),
+	}
+	ready := 0
+	for _, s := range pod.Status.ContainerStatuses {
+		if s.Ready {
+			ready++
+		}
+		answer.Restarts += s.RestartCount
+	}
+	for i := range pod.Spec.Containers {
+		answer.Images = append(answer.Images, pod.Spec.Containers[i].Image)
+	}
+	answer.Ready = fmt.Sprintf("%d/%d", ready, len(pod.Spec.Containers))
+	return answer
+}
+
+// ingressHosts returns the hosts of the ingresses named after the application or workload or routing to a service
+// with either name
+func ingressHosts(ingresses []networkingv1.Ingress, names ...string) []string {
+	matches := func(name string) bool {
+		for _, n := range names {
+			if n != "" && n == name {
+				return true
+			}
+		}
+		return false
+	}
+	var answer []string
+	for i := range ingresses {
+		ing := &ingresses[i]
+		found := matches(ing.Name)
+		if !found && ing.Spec.DefaultBackend != nil && ing.Spec.DefaultBackend.Service != nil {
+			found = matches(ing.Spec.DefaultBackend.Service.Name)
+		}
+		for _, rule := range ing.Spec.Rules {
+			if found || rule.HTTP == nil {
+				break
+			}
+			for _, p := range rule.HTTP.Paths {
+				if p.Backend.Service != nil && matches(p.Backend.Service.Name) {
+					found = true
+					break
+				}
+			}
+		}
+		if !found {
+			continue
+		}
+		for _, rule := range ing.Spec.Rules {
+			if rule.Host != "" {
+				answer = append(answer, rule.Host)
+			}
+		}
+	}
+	sort.Strings(answer)
+	return answer
+}
+
+// events returns the most recent events of the workload, its pods and the resources it creates which are named after it
+func (o *DescribeOptions) events(events []corev1.Event, ns, workload string, podNames []string) []DescribeEvent {
+	if o.Events <= 0 {
+		return nil
+	}
+	pods := map[string]bool{}
+	for _, name := range podNames {
+		pods[name] = true
+	}
+	var answer []DescribeEvent
+	for i := range events {
+		e := &events[i]
+		name := e.InvolvedObject.Name
+		if name != workload && !strings.HasPrefix(name, workload+"-") && !pods[name] {
+			continue
+		}
+		lastSeen := e.LastTimestamp
+		if lastSeen.IsZero() {
+			lastSeen = metav1.Time{Time: e.EventTime.Time}
+		}
+		answer = append(answer, DescribeEvent{
+			Type:     e.Type,
+			Reason:   e.Reason,
+			Object:   strings.ToLower(e.InvolvedObject.Kind) + "/" + name,
+			Message:  strings.TrimSpace(e.Message),
+			Count:    e.Count,
+			LastSeen: lastSeen,
+		})
+	}
+	sort.SliceStable(answer, func(i, j int) bool {
+		return answer[j].LastSeen.Before(&answer[i].LastSeen)
+	})
+	if len(answer) > o.Events {
+		answer = answer[:o.Events]
+	}
+	log.Logger().Debugf("found %d events for %s in namespace %s", len(answer), workload, ns)
+	return answer
+}
@@ -0,0 +1,121 @@
+package applications
+
+import (
+	"sort"
+	"strings"
+)
+
+const (
+	// OutputDiffKind the kind of the machine readable comparison of the applications in two environments
+	OutputDiffKind = "ApplicationDiff"
+)
+
+// DiffStatus how the version of an application in one environment compares to another
+type DiffStatus string
+
+const (
+	// DiffStatusIdentical the same version is deployed in both environments
+	DiffStatusIdentical DiffStatus = "identical"
+
+	// DiffStatusAhead the version in the from environment is newer than the version in the to environment
+	DiffStatusAhead DiffStatus = "ahead"
+
+	// DiffStatusBehind the version in the from environment is older than the version in the to environment
+	DiffStatusBehind DiffStatus = "behind"
+
+	// DiffStatusMissingPrefix the prefix of the status of an application which is not deployed in the environment
+	// named after the prefix, e.g. missing-in-production
+	DiffStatusMissingPrefix = "missing-in-"
+)
+
+// DiffStatusMissingIn returns the status of an application which is only deployed in the other environment
+func DiffStatusMissingIn(env string) DiffStatus {
+	return DiffStatus(DiffStatusMissingPrefix + env)
+}
+
+// IsMissing returns true if the application is only deployed in one of the environments
+func (s DiffStatus) IsMissing() bool {
+	return strings.HasPrefix(string(s), DiffStatusMissingPrefix)
+}
+
+// Diff the comparison of the versions of every application deployed in either of two environments
+type Diff struct {
+	APIVersion string     `json:"apiVersion"`
+	Kind       string     `json:"kind"`
+	From       string     `json:"from"`
+	To         string     `json:"to"`
+	Items      []DiffItem `json:"items"`
+}
+
+// DiffItem the versions of an application in the two environments
+type DiffItem struct {
+	Name        string     `json:"name"`
+	FromVersion string     `json:"fromVersion,omitempty"`
+	ToVersion   string     `json:"toVersion,omitempty"`
+	Status      DiffStatus `json:"status"`
+}
+
+// HasDrift returns true if any application is not identical in both environments
+func (d *Diff) HasDrift() bool {
+	for i := range d.Items {
+		if d.Items[i].Status != DiffStatusIdentical {
+			return true
+		}
+	}
+	return false
+}
+
+// Diff compares the version of every application deployed in either of the given environments ordered by name.
+// If an application has several workloads in an environment the newest version is used
+func (l *List) Diff(from, to string) Diff {
+	answer := Diff{
+		APIVersion: OutputAPIVersion,
+		Kind:       OutputDiffKind,
+		From:       from,
+		To:         to,
+		Items:      []DiffItem{},
+	}
+	for i := range l.Items {
+		a := &l.Items[i]
+		fromEnv, inFrom := a.Environments[from]
+		toEnv, inTo := a.Environments[to]
+		inFrom = inFrom && len(fromEnv.Deployments) > 0
+		inTo = inTo && len(toEnv.Deployments) > 0
+		if !inFrom && !inTo {
+			continue
+		}
+		item := DiffItem{
+			Name:        a.Name(),
+			FromVersion: newestVersion(fromEnv.Deployments),
+			ToVersion:   newestVersion(toEnv.Deployments),
+		}
+		switch {
+		case !inFrom:
+			item.Status = DiffStatusMissingIn(from)
+		case !inTo:
+			item.Status = DiffStatusMissingIn(to)
+		case CompareVersions(item.FromVersion, item.ToVersion) > 0:
+			item.Status = DiffStatusAhead
+		case CompareVersions(item.FromVersion, item.ToVersion) < 0:
+			item.Status = DiffStatusBehind
+		default:
+			item.Status = DiffStatusIdentical
+		}
+		answer.Items = append(answer.Items, item)
+	}
+	sort.SliceStable(answer.Items, func(i, j int) bool {
+		return answer.Items[i].Name < answer.Items[j].Name
+	})
+	return answer
+}
+
+// newestVersion returns the newest version of the deployments
+func newestVersion(deployments []Deployment) string {
+	answer := ""
+	for i := range deployments {
+		if CompareVersions(deployments[i].Version, answer) > 0 {
+			answer = deployments[i].Version
+		}
+	}
+	return answer
+}
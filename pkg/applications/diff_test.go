@@ -0,0 +1,53 @@
+package applications
+
+import (
+	"testing"
+
+	v1 "github.com/jenkins-x/jx-api/v4/pkg/apis/jenkins.io/v1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestListDiff(t *testing.T) {
+	app := func(name string, versions map[string][]string) Application {
+		a := Application{
+			SourceRepository: &v1.SourceRepository{Spec: v1.SourceRepositorySpec{Repo: name}},
+			Environments:     map[string]Environment{},
+		}
+		for env, vs := range versions {
+			e := Environment{Environment: v1.Environment{ObjectMeta: metav1.ObjectMeta{Name: env}}}
+			for _, v := range vs {
+				e.Deployments = append(e.Deployments, Deployment{Name: name, Version: v})
+			}
+			a.Environments[env] = e
+		}
+		return a
+	}
+	list := List{Items: []Application{
+		app("same", map[string][]string{"staging": {"1.0.0"}, "production": {"1.0.0"}}),
+		app("newer", map[string][]string{"staging": {"1.10.0"}, "production": {"1.9.0"}}),
+		app("older", map[string][]string{"staging": {"1.0.0"}, "production": {"1.0.1"}}),
+		app("new", map[string][]string{"staging": {"0.1.0"}}),
+		app("removed", map[string][]string{"staging": {}, "production": {"2.0.0"}}),
+		app("workers", map[string][]string{"staging": {"1.0.0", "1.2.0"}, "production": {"1.2.0"}}),
+		app("undeployed", map[string][]string{"dev": {"1.0.0"}}),
+	}}
+
+	d := list.Diff("staging", "production")
+	assert.Equal(t, OutputDiffKind, d.Kind)
+	assert.Equal(t, []DiffItem{
+		{Name: "new", FromVersion: "0.1.0", Status: "missing-in-production"},
+		{Name: "newer", FromVersion: "1.10.0", ToVersion: "1.9.0", Status: DiffStatusAhead},
+		{Name: "older", FromVersion: "1.0.0", ToVersion: "1.0.1", Status: DiffStatusBehind},
+		{Name: "removed", ToVersion: "2.0.0", Status: "missing-in-staging"},
+		{Name: "same", FromVersion: "1.0.0", ToVersion: "1.0.0", Status: DiffStatusIdentical},
+		{Name: "workers", FromVersion: "1.2.0", ToVersion: "1.2.0", Status: DiffStatusIdentical},
+	}, d.Items)
+	assert.True(t, d.HasDrift())
+	assert.True(t, d.Items[0].Status.IsMissing())
+	assert.False(t, d.Items[1].Status.IsMissing())
+
+	identical := List{Items: list.Items[:1]}
+	d = identical.Diff("staging", "production")
+	assert.False(t, d.HasDrift())
+}
@@ -0,0 +1,200 @@
+package applications
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/jenkins-x/go-scm/scm"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/giturl"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/scmhelpers"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+)
+
+const (
+	// ReleasesPath the path of the release report inside an environment git repository
+	ReleasesPath = "docs/releases.yaml"
+
+	// FetcherClone fetches the release report by cloning the environment git repository
+	FetcherClone = "clone"
+
+	// FetcherSparse fetches the release report using a shallow sparse checkout of the environment git repository
+	FetcherSparse = "sparse"
+
+	// FetcherScm fetches the release report using the git provider's REST API
+	FetcherScm = "scm"
+
+	// FetcherAuto tries the git provider's REST API, then a sparse checkout and finally a clone
+	FetcherAuto = "auto"
+)
+
+// Fetchers the names of the supported release fetchers
+var Fetchers = []string{FetcherAuto, FetcherClone, FetcherSparse, FetcherScm}
+
+// ReleaseFetcher fetches the release report of a remote environment git repository
+type ReleaseFetcher interface {
+	// FetchReleases returns the contents of the release report or nil if the repository does not have one
+	FetchReleases(ctx context.Context, gitURL string) ([]byte, error)
+}
+
+// NewReleaseFetcher creates the release fetcher of the given name. A nil git client means a git client bound to
+// the context of each fetch is used
+func NewReleaseFetcher(name string, g gitclient.Interface, cache *RemoteCache) (ReleaseFetcher, error) {
+	switch name {
+	case FetcherClone, "":
+		return &CloneReleaseFetcher{GitClient: g, Cache: cache}, nil
+	case FetcherSparse:
+		return &SparseReleaseFetcher{GitClient: g}, nil
+	case FetcherScm:
+		return &ScmReleaseFetcher{}, nil
+	case FetcherAuto:
+		return FallbackReleaseFetcher{
+			&ScmReleaseFetcher{},
+			&SparseReleaseFetcher{GitClient: g},
+			&CloneReleaseFetcher{GitClient: g, Cache: cache},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown release fetcher %s, supported values are %v", name, Fetchers)
+	}
+}
+
+// CloneReleaseFetcher reads the release report from a full clone of the repository which is cached if the cache
+// is not nil
+type CloneReleaseFetcher struct {
+	GitClient gitclient.Interface
+	Cache     *RemoteCache
+}
+
+// FetchReleases clones the repository then reads the release report
+func (f *CloneReleaseFetcher) FetchReleases(ctx context.Context, gitURL string) ([]byte, error) {
+	dir, done, err := f.Cache.Clone(contextGitClient(ctx, f.GitClient), gitURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to clone git URL %s: %w", gitURL, err)
+	}
+	defer done()
+	return readReleases(dir, gitURL)
+}
+
+// SparseReleaseFetcher reads the release report from a shallow sparse checkout of just the release report
+type SparseReleaseFetcher struct {
+	GitClient gitclient.Interface
+}
+
+// FetchReleases checks out the release report into a temporary directory then reads it
+func (f *SparseReleaseFetcher) FetchReleases(ctx context.Context, gitURL string) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "jx-application-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	_, err = gitclient.SparseCloneToDir(contextGitClient(ctx, f.GitClient), gitURL, dir, true, "/"+ReleasesPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sparse checkout git URL %s: %w", gitURL, err)
+	}
+	return readReleases(dir, gitURL)
+}
+
+// ScmReleaseFetcher reads the release report using the contents API of the git provider
+type ScmReleaseFetcher struct {
+	// ScmClient the client to use. If nil a client is created for each git server using the git credentials
+	ScmClient *scm.Client
+
+	// Ref the branch, tag or sha to read. If blank the default branch is used
+	Ref string
+
+	lock    sync.Mutex
+	clients map[string]*scm.Client
+}
+
+// FetchReleases finds the release report using the git provider's REST API
+func (f *ScmReleaseFetcher) FetchReleases(ctx context.Context, gitURL string) ([]byte, error) {
+	gitInfo, err := giturl.ParseGitURL(gitURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse git URL %s: %w", gitURL, err)
+	}
+	scmClient, err := f.scmClient(gitInfo.HostURLWithoutUser())
+	if err != nil {
+		return nil, err
+	}
+	fullName := scm.Join(gitInfo.Organisation, gitInfo.Name)
+	content, res, err := scmClient.Contents.Find(ctx, fullName, ReleasesPath, f.Ref)
+	if scmhelpers.IsScmResponseNotFound(res) || scmhelpers.IsScmNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find %s in repository %s: %w", ReleasesPath, fullName, err)
+	}
+	return content.Data, nil
+}
+
+func (f *ScmReleaseFetcher) scmClient(serverURL string) (*scm.Client, error) {
+	if f.ScmClient != nil {
+		return f.ScmClient, nil
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.clients == nil {
+		f.clients = map[string]*scm.Client{}
+	}
+	if c := f.clients[serverURL]; c != nil {
+		return c, nil
+	}
+	factory := &scmhelpers.Factory{GitServerURL: serverURL}
+	c, err := factory.Create()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create git provider client for %s: %w", serverURL, err)
+	}
+	f.clients[serverURL] = c
+	return c, nil
+}
+
+// FallbackReleaseFetcher tries each fetcher in turn until one succeeds
+type FallbackReleaseFetcher []ReleaseFetcher
+
+// FetchReleases returns the release report from the first fetcher which succeeds
+func (f FallbackReleaseFetcher) FetchReleases(ctx context.Context, gitURL string) ([]byte, error) {
+	var errs []error
+	for _, fetcher := range f {
+		data, err := fetcher.FetchReleases(ctx, gitURL)
+		if err == nil {
+			return data, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		log.Logger().Debugf("failed to fetch releases of %s with %T so falling back: %s", gitURL, fetcher, err.Error())
+		errs = append(errs, err)
+	}
+	return nil, errors.Join(errs...)
+}
+
+// contextGitClient returns the git client or one bound to the context if it is nil
+func contextGitClient(ctx context.Context, g gitclient.Interface) gitclient.Interface {
+	if g == nil {
+		return NewContextGitClient(ctx)
+	}
+	return g
+}
+
+// readReleases reads the release report in the checkout returning nil if it does not exist
+func readReleases(dir, gitURL string) ([]byte, error) {
+	path := filepath.Join(dir, filepath.FromSlash(ReleasesPath))
+	exists, err := files.FileExists(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for file %s in git clone of %s: %w", path, gitURL, err)
+	}
+	if !exists {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return data, nil
+}
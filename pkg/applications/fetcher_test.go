@@ -0,0 +1,86 @@
+package applications
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jenkins-x/go-scm/scm/driver/fake"
+	v1 "github.com/jenkins-x/jx-api/v4/pkg/apis/jenkins.io/v1"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/cli"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type failingReleaseFetcher struct{}
+
+func (failingReleaseFetcher) FetchReleases(context.Context, string) ([]byte, error) {
+	return nil, errors.New("boom")
+}
+
+func TestReleaseFetchers(t *testing.T) {
+	ctx := context.Background()
+	g := cli.NewCLIClient("", cmdrunner.QuietCommandRunner)
+	repoDir := t.TempDir()
+	initTestGitRepository(t, g, repoDir)
+	commitTestRelease(t, g, repoDir, "1.0.0")
+	bareDir := filepath.Join(t.TempDir(), "env-production.git")
+	_, err := g.Command(repoDir, "clone", "--bare", repoDir, bareDir)
+	require.NoError(t, err)
+	gitURL := "file://" + bareDir
+
+	env := &v1.Environment{
+		ObjectMeta: metav1.ObjectMeta{Name: "production"},
+		Spec: v1.EnvironmentSpec{
+			Namespace:     "jx-production",
+			RemoteCluster: true,
+			Source:        v1.EnvironmentRepository{URL: gitURL},
+		},
+	}
+
+	t.Run("sparse", func(t *testing.T) {
+		deployments, err := GetRemoteDeploymentsWithFetcher(ctx, &SparseReleaseFetcher{GitClient: g}, env)
+		require.NoError(t, err)
+		assert.Equal(t, "1.0.0", deployments["myapp"].Version)
+	})
+
+	t.Run("fallback", func(t *testing.T) {
+		fetcher := FallbackReleaseFetcher{failingReleaseFetcher{}, &CloneReleaseFetcher{GitClient: g}}
+		deployments, err := GetRemoteDeploymentsWithFetcher(ctx, fetcher, env)
+		require.NoError(t, err)
+		assert.Equal(t, "1.0.0", deployments["myapp"].Version)
+
+		_, err = GetRemoteDeploymentsWithFetcher(ctx, FallbackReleaseFetcher{failingReleaseFetcher{}}, env)
+		assert.Error(t, err)
+	})
+
+	t.Run("scm", func(t *testing.T) {
+		scmClient, fakeData := fake.NewDefault()
+		fakeData.ContentDir = t.TempDir()
+		data, err := os.ReadFile(filepath.Join(repoDir, "docs", "releases.yaml"))
+		require.NoError(t, err)
+		path := filepath.Join(fakeData.ContentDir, "myorg", "env-production", "docs", "releases.yaml")
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, data, 0o600))
+
+		fetcher := &ScmReleaseFetcher{ScmClient: scmClient}
+		scmEnv := env.DeepCopy()
+		scmEnv.Spec.Source.URL = "https://github.com/myorg/env-production.git"
+		deployments, err := GetRemoteDeploymentsWithFetcher(ctx, fetcher, scmEnv)
+		require.NoError(t, err)
+		assert.Equal(t, "1.0.0", deployments["myapp"].Version)
+
+		// a repository without a release report has no deployments
+		scmEnv.Spec.Source.URL = "https://github.com/myorg/env-staging.git"
+		deployments, err = GetRemoteDeploymentsWithFetcher(ctx, fetcher, scmEnv)
+		require.NoError(t, err)
+		assert.Empty(t, deployments)
+	})
+
+	_, err = NewReleaseFetcher("carrier-pigeon", g, nil)
+	assert.Error(t, err)
+}
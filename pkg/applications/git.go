@@ -0,0 +1,49 @@
+package applications
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/cli"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+)
+
+// NewContextGitClient creates a git client whose commands are killed when the context is cancelled or times out
+func NewContextGitClient(ctx context.Context) gitclient.Interface {
+	return cli.NewCLIClient("", ContextCommandRunner(ctx))
+}
+
+// ContextCommandRunner returns a quiet command runner which kills the command when the context is done
+func ContextCommandRunner(ctx context.Context) cmdrunner.CommandRunner {
+	return func(c *cmdrunner.Command) (string, error) {
+		log.Logger().Debugf("about to run: %s in dir %s", termcolor.ColorInfo(cmdrunner.CLI(c)), termcolor.ColorInfo(c.Dir))
+
+		e := exec.CommandContext(ctx, c.Name, c.Args...) // #nosec
+		e.Dir = c.Dir
+		e.Stdin = c.In
+		if len(c.Env) > 0 {
+			e.Env = os.Environ()
+			for k, v := range c.Env {
+				e.Env = append(e.Env, k+"="+v)
+			}
+		}
+		data, err := e.CombinedOutput()
+		text := strings.TrimSpace(string(data))
+		if err != nil {
+			if ctx.Err() != nil {
+				err = ctx.Err()
+			}
+			return text, fmt.Errorf("failed to run '%s' command in directory '%s', output: '%s': %w", cmdrunner.CLI(c), c.Dir, text, err)
+		}
+		if text != "" {
+			log.Logger().Debug(termcolor.ColorStatus(text))
+		}
+		return text, nil
+	}
+}
@@ -0,0 +1,275 @@
+package applications
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// HelmReleaseSecretType the type of the secrets helm 3 stores its releases in
+	HelmReleaseSecretType = "helm.sh/release.v1"
+
+	// HelmReleaseSelector the label selector of the secrets helm 3 stores its releases in
+	HelmReleaseSelector = "owner=helm"
+
+	// HelmInstanceLabel the recommended kubernetes label helm charts set to the name of the release
+	HelmInstanceLabel = "app.kubernetes.io/instance"
+
+	// HelmReleaseStatusDeployed the status of the helm release currently deployed
+	HelmReleaseStatusDeployed = "deployed"
+
+	// HelmReleaseStatusFailed the status of a helm release which failed to install or upgrade
+	HelmReleaseStatusFailed = "failed"
+
+	// KindHelmRelease the kind of a failed or pending helm release which has no workloads
+	KindHelmRelease = "HelmRelease"
+
+	// helmReleaseStatusPendingPrefix the prefix of the statuses of a helm release being installed, upgraded or
+	// rolled back
+	helmReleaseStatusPendingPrefix = "pending-"
+)
+
+var gzipHeader = []byte{0x1f, 0x8b, 0x08}
+
+// HelmRelease a revision of a helm 3 release decoded from its release secret
+type HelmRelease struct {
+	Name         string `json:"name"`
+	Namespace    string `json:"namespace,omitempty"`
+	Revision     int    `json:"revision"`
+	Status       string `json:"status,omitempty"`
+	Chart        string `json:"chart,omitempty"`
+	ChartVersion string `json:"chartVersion,omitempty"`
+	AppVersion   string `json:"appVersion,omitempty"`
+	// LastDeployed when the revision was deployed
+	LastDeployed *metav1.Time `json:"lastDeployed,omitempty"`
+}
+
+// HelmReleases the revisions of the helm releases in a namespace with the newest revisions first
+type HelmReleases []*HelmRelease
+
+// helmReleaseRecord the subset of the release helm stores in its release secrets
+type helmReleaseRecord struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Version   int    `json:"version"`
+	Info      struct {
+		Status       string `json:"status"`
+		LastDeployed string `json:"last_deployed"`
+	} `json:"info"`
+	Chart struct {
+		Metadata struct {
+			Name       string `json:"name"`
+			Version    string `json:"version"`
+			AppVersion string `json:"appVersion"`
+		} `json:"metadata"`
+	} `json:"chart"`
+}
+
+// DecodeHelmRelease decodes the base64 encoded and usually gzipped release helm stores in the release key of its
+// release secrets
+func DecodeHelmRelease(data []byte) (*HelmRelease, error) {
+	b, err := base64.StdEncoding.DecodeString(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to base64 decode the helm release: %w", err)
+	}
+	if bytes.HasPrefix(b, gzipHeader) {
+		r, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return nil, fmt.Errorf("failed to uncompress the helm release: %w", err)
+		}
+		defer r.Close()
+		b, err = io.ReadAll(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to uncompress the helm release: %w", err)
+		}
+	}
+	record := &helmReleaseRecord{}
+	err = json.Unmarshal(b, record)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal the helm release: %w", err)
+	}
+	release := &HelmRelease{
+		Name:         record.Name,
+		Namespace:    record.Namespace,
+		Revision:     record.Version,
+		Status:       record.Info.Status,
+		Chart:        record.Chart.Metadata.Name,
+		ChartVersion: record.Chart.Metadata.Version,
+		AppVersion:   record.Chart.Metadata.AppVersion,
+	}
+	if record.Info.LastDeployed != "" {
+		t, err := time.Parse(time.RFC3339Nano, record.Info.LastDeployed)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the last deployed time %s of the helm release: %w", record.Info.LastDeployed, err)
+		}
+		release.LastDeployed = &metav1.Time{Time: t}
+	}
+	return release, nil
+}
+
+// IsFailed returns true if the release failed to install or upgrade
+func (r *HelmRelease) IsFailed() bool {
+	return r.Status == HelmReleaseStatusFailed
+}
+
+// IsPending returns true if the release is being installed, upgraded or rolled back
+func (r *HelmRelease) IsPending() bool {
+	return strings.HasPrefix(r.Status, helmReleaseStatusPendingPrefix)
+}
+
+// NewHelmReleases decodes the latest and deployed revisions of the helm release secrets skipping any other secrets or
+// releases which cannot be decoded
+func NewHelmReleases(secrets []*corev1.Secret) HelmReleases {
+	var answer HelmReleases
+	for _, s := range latestHelmReleaseSecrets(secrets) {
+		release, err := DecodeHelmRelease(s.Data["release"])
+		if err != nil {
+			log.Logger().Debugf("ignoring helm release secret %s in namespace %s: %s", s.Name, s.Namespace, err.Error())
+			continue
+		}
+		answer = append(answer, release)
+	}
+	sort.SliceStable(answer, func(i, j int) bool {
+		if answer[i].Name != answer[j].Name {
+			return answer[i].Name < answer[j].Name
+		}
+		return answer[i].Revision > answer[j].Revision
+	})
+	return answer
+}
+
+// latestHelmReleaseSecrets returns the release secrets of the latest and deployed revision of each release using the
+// labels helm sets on them so that the older revisions are not decoded. Secrets without the labels are all returned
+func latestHelmReleaseSecrets(secrets []*corev1.Secret) []*corev1.Secret {
+	type revision struct {
+		secret  *corev1.Secret
+		version int
+	}
+	latest := map[string]revision{}
+	deployed := map[string]revision{}
+	var answer []*corev1.Secret
+	for _, s := range secrets {
+		if s.Type != HelmReleaseSecretType {
+			continue
+		}
+		name := s.Labels["name"]
+		version, err := strconv.Atoi(s.Labels["version"])
+		if name == "" || err != nil {
+			answer = append(answer, s)
+			continue
+		}
+		if r, ok := latest[name]; !ok || version > r.version {
+			latest[name] = revision{secret: s, version: version}
+		}
+		if s.Labels["status"] != HelmReleaseStatusDeployed {
+			continue
+		}
+		if r, ok := deployed[name]; !ok || version > r.version {
+			deployed[name] = revision{secret: s, version: version}
+		}
+	}
+	for name, r := range latest {
+		answer = append(answer, r.secret)
+		if d, ok := deployed[name]; ok && d.secret != r.secret {
+			answer = append(answer, d.secret)
+		}
+	}
+	return answer
+}
+
+// Latest returns the newest revision of the release or nil if there is none
+func (r HelmReleases) Latest(name string) *HelmRelease {
+	for _, release := range r {
+		if release.Name == name {
+			return release
+		}
+	}
+	return nil
+}
+
+// Deployed returns the revision of the release which is deployed or nil if there is none
+func (r HelmReleases) Deployed(name string) *HelmRelease {
+	for _, release := range r {
+		if release.Name == name && release.Status == HelmReleaseStatusDeployed {
+			return release
+		}
+	}
+	return nil
+}
+
+// helmReleaseName returns the name of the helm release which created the resource or blank if it was not created
+// by helm
+func helmReleaseName(meta *metav1.ObjectMeta) string {
+	if name := meta.Annotations[HelmReleaseNameAnnotation]; name != "" {
+		return name
+	}
+	if meta.Labels["app.kubernetes.io/managed-by"] == "Helm" {
+		return meta.Labels[HelmInstanceLabel]
+	}
+	return ""
+}
+
+// getHelmReleases returns the helm releases in the namespace. Nothing is returned if the user is not allowed to
+// list secrets
+func getHelmReleases(ctx context.Context, kubeClient kubernetes.Interface, ns string) (HelmReleases, error) {
+	list, err := kubeClient.CoreV1().Secrets(ns).List(ctx, metav1.ListOptions{LabelSelector: HelmReleaseSelector})
+	if err != nil {
+		if skipWorkloadError("Secret", ns, err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to list helm release secrets in namespace %s: %w", ns, err)
+	}
+	secrets := make([]*corev1.Secret, 0, len(list.Items))
+	for i := range list.Items {
+		secrets = append(secrets, &list.Items[i])
+	}
+	return NewHelmReleases(secrets), nil
+}
+
+// applyHelmReleases links the workloads to the helm release which created them. A workload is added for each failed
+// or pending release which has none so that a release which failed to install is still shown
+func applyHelmReleases(workloads []*Workload, releases HelmReleases) []*Workload {
+	if len(releases) == 0 {
+		return workloads
+	}
+	linked := map[string]bool{}
+	for _, w := range workloads {
+		name := helmReleaseName(w.ObjectMeta)
+		if name != "" {
+			w.HelmRelease = releases.Latest(name)
+			w.DeployedHelmRelease = releases.Deployed(name)
+			linked[name] = true
+		}
+	}
+	for _, r := range releases {
+		if linked[r.Name] || !(r.IsFailed() || r.IsPending()) {
+			continue
+		}
+		linked[r.Name] = true
+		workloads = append(workloads, &Workload{
+			Kind: KindHelmRelease,
+			ObjectMeta: &metav1.ObjectMeta{
+				Name:        r.Name,
+				Namespace:   r.Namespace,
+				Annotations: map[string]string{HelmReleaseNameAnnotation: r.Name},
+			},
+			HelmRelease:         r,
+			DeployedHelmRelease: releases.Deployed(r.Name),
+		})
+	}
+	return workloads
+}
@@ -0,0 +1,154 @@
+package applications
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	v1 "github.com/jenkins-x/jx-api/v4/pkg/apis/jenkins.io/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// newTestHelmReleaseSecret creates a release secret encoded the way helm 3 stores it
+func newTestHelmReleaseSecret(t *testing.T, ns, name string, revision int, status, chartVersion, appVersion string) *corev1.Secret {
+	release := map[string]interface{}{
+		"name":      name,
+		"namespace": ns,
+		"version":   revision,
+		"info":      map[string]interface{}{"status": status, "last_deployed": "2024-05-01T10:00:00.123456789Z"},
+		"chart": map[string]interface{}{
+			"metadata": map[string]interface{}{"name": name, "version": chartVersion, "appVersion": appVersion},
+		},
+	}
+	data, err := json.Marshal(release)
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err = w.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "sh.helm.release.v1." + name + ".v" + strconv.Itoa(revision),
+			Namespace: ns,
+			Labels:    map[string]string{"owner": "helm", "name": name, "status": status, "version": strconv.Itoa(revision)},
+		},
+		Type: HelmReleaseSecretType,
+		Data: map[string][]byte{"release": []byte(base64.StdEncoding.EncodeToString(buf.Bytes()))},
+	}
+}
+
+func TestNewHelmReleases(t *testing.T) {
+	ns := "jx-staging"
+	releases := NewHelmReleases([]*corev1.Secret{
+		newTestHelmReleaseSecret(t, ns, "myapp", 1, "superseded", "0.1.0", "1.0.0"),
+		newTestHelmReleaseSecret(t, ns, "myapp", 3, "failed", "0.3.0", "1.2.0"),
+		newTestHelmReleaseSecret(t, ns, "myapp", 2, HelmReleaseStatusDeployed, "0.2.0", "1.1.0"),
+		{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: ns}, Type: corev1.SecretTypeOpaque},
+	})
+	require.Len(t, releases, 2, "should only decode the latest and deployed revisions")
+	lastDeployed := metav1.NewTime(time.Date(2024, 5, 1, 10, 0, 0, 123456789, time.UTC))
+	assert.Equal(t, &HelmRelease{Name: "myapp", Namespace: ns, Revision: 3, Status: "failed", Chart: "myapp", ChartVersion: "0.3.0", AppVersion: "1.2.0", LastDeployed: &lastDeployed}, releases.Latest("myapp"))
+	assert.True(t, releases.Latest("myapp").IsFailed())
+	assert.Equal(t, 2, releases.Deployed("myapp").Revision)
+	assert.Nil(t, releases.Latest("unknown"))
+
+	_, err := DecodeHelmRelease([]byte("not base64!"))
+	assert.Error(t, err)
+}
+
+func TestGetDeploymentsVersionSources(t *testing.T) {
+	ns := "jx-staging"
+	container := func(image string) corev1.PodSpec {
+		return corev1.PodSpec{Containers: []corev1.Container{{Name: "sidecar", Image: "envoy:1.30"}, {Name: "main", Image: image}}}
+	}
+
+	labelled := newTestDeployment("labelled", ns, "")
+	labelled.Labels = map[string]string{AppVersionLabel: "2.0.0"}
+	labelled.Spec.Template.Spec = container("ghcr.io/myorg/labelled:2.0.1")
+
+	released := newTestDeployment("released", ns, "")
+	released.Labels = nil
+	released.Annotations = map[string]string{HelmReleaseNameAnnotation: "released"}
+	released.Spec.Template.Spec = container("ghcr.io/myorg/released:3.0.0")
+
+	imaged := newTestDeployment("main", ns, "")
+	imaged.Labels = nil
+	imaged.Spec.Template.Spec = container("ghcr.io/myorg/main@sha256:abcdef")
+
+	kubeClient := fake.NewSimpleClientset(labelled, released, imaged,
+		newTestHelmReleaseSecret(t, ns, "released", 1, HelmReleaseStatusDeployed, "0.1.0", "3.0.0"),
+		newTestHelmReleaseSecret(t, ns, "released", 2, "pending-upgrade", "0.2.0", "3.1.0"),
+		newTestHelmReleaseSecret(t, ns, "broken", 1, HelmReleaseStatusFailed, "0.1.0", "1.0.0"),
+		newTestHelmReleaseSecret(t, ns, "healthy", 1, HelmReleaseStatusDeployed, "0.1.0", "1.0.0"))
+	env := &v1.Environment{
+		ObjectMeta: metav1.ObjectMeta{Name: "staging"},
+		Spec:       v1.EnvironmentSpec{Namespace: ns},
+	}
+
+	deployments, err := getDeployments(context.TODO(), kubeClient, nil, nil, ns, env, "")
+	require.NoError(t, err)
+
+	d := deployments["labelled"]
+	assert.Equal(t, "2.0.0", d.Version)
+	assert.Equal(t, LabelVersionSource(AppVersionLabel), d.VersionSource)
+	assert.Equal(t, "envoy:1.30", d.Image, "the first container should be used if none is named after the workload")
+	assert.True(t, d.ImageVersionMismatch())
+
+	d = deployments["released"]
+	assert.Equal(t, "3.0.0", d.Version, "the version should come from the deployed revision")
+	assert.Equal(t, VersionSourceHelmRelease, d.VersionSource)
+	require.NotNil(t, d.Helm)
+	assert.Equal(t, 2, d.Helm.Revision)
+	assert.True(t, d.Helm.IsPending())
+
+	d = deployments["HelmRelease/broken"]
+	assert.Equal(t, KindHelmRelease, d.Kind, "a failed release without workloads should still be shown")
+	assert.Equal(t, "broken", d.Name)
+	require.NotNil(t, d.Helm)
+	assert.Equal(t, HelmReleaseStatusFailed, d.Helm.Status)
+	assert.NotContains(t, deployments, "HelmRelease/healthy")
+
+	d = deployments["main"]
+	assert.Equal(t, "sha256:abcdef", d.Version)
+	assert.Equal(t, VersionSourceImage, d.VersionSource)
+	assert.False(t, d.ImageVersionMismatch())
+}
+
+func TestVersionFromLabels(t *testing.T) {
+	tests := []struct {
+		labels  map[string]string
+		version string
+		source  string
+	}{
+		{labels: map[string]string{"version": "1.0.0", AppVersionLabel: "2.0.0"}, version: "1.0.0", source: "label:version"},
+		{labels: map[string]string{AppVersionLabel: "2.0.0"}, version: "2.0.0", source: "label:app.kubernetes.io/version"},
+		{labels: map[string]string{"chart": "my-app-1.2.3"}, version: "1.2.3", source: "label:chart"},
+		{labels: map[string]string{HelmChartLabel: "my-app-1.2.3-rc1"}, version: "1.2.3-rc1", source: "label:helm.sh/chart"},
+		{labels: map[string]string{HelmChartLabel: "app-2fa-1.0.0"}, version: "1.0.0", source: "label:helm.sh/chart"},
+		{labels: map[string]string{HelmChartLabel: "jx-3scale-operator-0.4.1"}, version: "0.4.1", source: "label:helm.sh/chart"},
+		{labels: map[string]string{HelmChartLabel: "myapp-2-1.0.0-beta-1"}, version: "1.0.0-beta-1", source: "label:helm.sh/chart"},
+		{labels: map[string]string{"chart": "myapp-latest"}, version: "latest", source: "label:chart"},
+		{labels: map[string]string{RevisionLabel: "myfunc-00002"}, version: "00002", source: "label:serving.knative.dev/revision"},
+		{labels: nil},
+	}
+	for _, tt := range tests {
+		version, source := versionFromLabels(tt.labels)
+		assert.Equal(t, tt.version, version, "labels %v", tt.labels)
+		assert.Equal(t, tt.source, source, "labels %v", tt.labels)
+	}
+
+	assert.Equal(t, "1.0.0", ImageVersion("localhost:5000/myorg/myapp:1.0.0"))
+	assert.Equal(t, "", ImageVersion("localhost:5000/myorg/myapp"))
+	assert.Equal(t, "1.0.0", ImageVersion("myapp:1.0.0@sha256:abc"))
+}
@@ -0,0 +1,406 @@
+package applications
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/releasereport"
+	v1 "github.com/jenkins-x/jx-api/v4/pkg/apis/jenkins.io/v1"
+	jxc "github.com/jenkins-x/jx-api/v4/pkg/client/clientset/versioned"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/stringhelpers"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// OutputHistoryKind the kind of the machine readable promotion history of an application
+	OutputHistoryKind = "ApplicationHistory"
+
+	// HistorySourcePipelineActivity a promotion found in the promote steps of a PipelineActivity
+	HistorySourcePipelineActivity = "pipeline-activity"
+
+	// HistorySourceGit a promotion found in the changes to the release report of an environment git repository
+	HistorySourceGit = "git"
+
+	// DefaultHistoryMaxCommits the default number of the most recent changes to the release report of each environment
+	// git repository which are read
+	DefaultHistoryMaxCommits = 200
+)
+
+// History the promotions of an application across its environments ordered by time
+type History struct {
+	APIVersion string         `json:"apiVersion"`
+	Kind       string         `json:"kind"`
+	Name       string         `json:"name"`
+	Entries    []HistoryEntry `json:"entries"`
+}
+
+// HistoryEntry a version of an application reaching an environment
+type HistoryEntry struct {
+	Version        string       `json:"version"`
+	Environment    string       `json:"environment"`
+	Timestamp      *metav1.Time `json:"timestamp,omitempty"`
+	Status         string       `json:"status,omitempty"`
+	PullRequestURL string       `json:"pullRequestURL,omitempty"`
+	MergeCommitSHA string       `json:"mergeCommitSHA,omitempty"`
+
+	// MergedBy the author of the commit which changed the release report. For squash and rebase merges this is the
+	// author of the pull request rather than the person who merged it
+	MergedBy string   `json:"mergedBy,omitempty"`
+	Sources  []string `json:"sources"`
+}
+
+// HistoryOptions the clients and settings used to find the promotion history of an application
+type HistoryOptions struct {
+	JXClient jxc.Interface
+
+	// GitClient the git client used to read the history of environment git repositories. If nil a git client bound
+	// to the context is used
+	GitClient gitclient.Interface
+
+	// Namespace the development namespace containing the SourceRepositories, Environments and PipelineActivities
+	Namespace string
+
+	// Environments if not empty only the promotions to environments with these names are included
+	Environments []string
+
+	// Version if not empty only the promotions of this version are included
+	Version string
+
+	// SkipGit disables reading the history of the environment git repositories
+	SkipGit bool
+
+	// RemoteCache if not nil reuses the clones of environment git repositories between invocations
+	RemoteCache *RemoteCache
+
+	// MaxCommits the maximum number of the most recent changes to the release report of each environment git
+	// repository which are read. If zero DefaultHistoryMaxCommits is used
+	MaxCommits int
+}
+
+// History reconstructs the promotion timeline of the application with the given name from the promote steps of its
+// PipelineActivities and the changes to the release reports of the environment git repositories
+func (o *HistoryOptions) History(ctx context.Context, name string) (*History, error) {
+	ns := o.Namespace
+	srList, err := o.JXClient.JenkinsV1().SourceRepositories(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find any SourceRepositories in namespace %s: %w", ns, err)
+	}
+	envMap, err := getEnvironments(ctx, o.JXClient, ns)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch environments in namespace %s: %w", ns, err)
+	}
+	permanentEnvsMap := PermanentEnvironments(envMap)
+	list := NewList(srList.Items, permanentEnvsMap, nil)
+	app := list.Find(name)
+	if app == nil {
+		return nil, fmt.Errorf("no application called %s could be found in namespace %s", name, ns)
+	}
+
+	var envs []*v1.Environment
+	for _, env := range permanentEnvsMap {
+		if env.Spec.Kind == v1.EnvironmentKindTypeDevelopment {
+			continue
+		}
+		if len(o.Environments) > 0 && stringhelpers.StringArrayIndex(o.Environments, env.Name) < 0 {
+			continue
+		}
+		envs = append(envs, env)
+	}
+	sortEnvironments(envs)
+
+	entries, err := o.pipelineActivityEntries(ctx, app, envs)
+	if err != nil {
+		return nil, err
+	}
+	if !o.SkipGit {
+		entries, err = o.gitEntries(ctx, app, envs, entries)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	answer := &History{
+		APIVersion: OutputAPIVersion,
+		Kind:       OutputHistoryKind,
+		Name:       app.Name(),
+		Entries:    []HistoryEntry{},
+	}
+	for i := range entries {
+		if o.Version == "" || entries[i].Version == o.Version {
+			answer.Entries = append(answer.Entries, entries[i])
+		}
+	}
+	sortHistory(answer.Entries, envs)
+	return answer, nil
+}
+
+// pipelineActivityEntries returns a promotion for each promote step of the PipelineActivities of the application's
+// repository which targets one of the environments
+func (o *HistoryOptions) pipelineActivityEntries(ctx context.Context, app *Application, envs []*v1.Environment) ([]HistoryEntry, error) {
+	ns := o.Namespace
+	list, err := o.JXClient.JenkinsV1().PipelineActivities(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list PipelineActivities in namespace %s: %w", ns, err)
+	}
+	envNames := map[string]bool{}
+	for _, env := range envs {
+		envNames[env.Name] = true
+	}
+	sr := app.SourceRepository
+	var answer []HistoryEntry
+	for i := range list.Items {
+		pa := &list.Items[i]
+		owner := pa.Spec.GitOwner
+		if owner == "" {
+			owner = pa.Labels["owner"]
+		}
+		repo := pa.Spec.GitRepository
+		if repo == "" {
+			repo = pa.Labels["repository"]
+		}
+		if owner != sr.Spec.Org || repo != sr.Spec.Repo {
+			continue
+		}
+		for _, step := range pa.Spec.Steps {
+			promote := step.Promote
+			if promote == nil || !envNames[promote.Environment] {
+				continue
+			}
+			entry := HistoryEntry{
+				Version:     pa.Spec.Version,
+				Environment: promote.Environment,
+				Status:      string(promote.Status),
+				Timestamp:   stepTimestamp(&promote.CoreActivityStep),
+				Sources:     []string{HistorySourcePipelineActivity},
+			}
+			if pr := promote.PullRequest; pr != nil {
+				entry.PullRequestURL = pr.PullRequestURL
+				entry.MergeCommitSHA = pr.MergeCommitSHA
+				if pr.CompletedTimestamp != nil {
+					entry.Timestamp = pr.CompletedTimestamp
+				}
+			}
+			if entry.Timestamp == nil {
+				entry.Timestamp = pa.Spec.CompletedTimestamp
+			}
+			answer = append(answer, entry)
+		}
+	}
+	log.Logger().Debugf("found %d promotions of %s in PipelineActivities", len(answer), app.Name())
+	return answer, nil
+}
+
+// stepTimestamp returns when the step completed or started if it is still running
+func stepTimestamp(step *v1.CoreActivityStep) *metav1.Time {
+	if step.CompletedTimestamp != nil {
+		return step.CompletedTimestamp
+	}
+	return step.StartedTimestamp
+}
+
+// gitEntries merges the changes to the application's version in the release report of each environment git
+// repository into the promotions. A change to the same version of an environment as a promotion which was not already
+// found in git completes that promotion, otherwise a new promotion is added
+func (o *HistoryOptions) gitEntries(ctx context.Context, app *Application, envs []*v1.Environment, entries []HistoryEntry) ([]HistoryEntry, error) {
+	envsByURL := map[string][]*v1.Environment{}
+	var urls []string
+	for _, env := range envs {
+		gitURL := env.Spec.Source.URL
+		if gitURL == "" {
+			continue
+		}
+		if _, ok := envsByURL[gitURL]; !ok {
+			urls = append(urls, gitURL)
+		}
+		envsByURL[gitURL] = append(envsByURL[gitURL], env)
+	}
+
+	maxCommits := o.MaxCommits
+	if maxCommits <= 0 {
+		maxCommits = DefaultHistoryMaxCommits
+	}
+	g := contextGitClient(ctx, o.GitClient)
+	releaseNames := []string{app.Name()}
+	if app.AppName == "" && app.SourceRepository.Spec.Repo != app.Name() {
+		releaseNames = append(releaseNames, app.SourceRepository.Spec.Repo)
+	}
+	for _, gitURL := range urls {
+		dir, done, err := o.RemoteCache.Clone(g, gitURL)
+		if err != nil {
+			return entries, fmt.Errorf("failed to clone git URL %s: %w", gitURL, err)
+		}
+		commits, err := releaseCommits(ctx, g, dir, maxCommits)
+		if err != nil {
+			done()
+			return entries, fmt.Errorf("failed to read the history of %s in %s: %w", ReleasesPath, gitURL, err)
+		}
+		for _, env := range envsByURL[gitURL] {
+			for _, e := range envReleaseChanges(commits, env, releaseNames) {
+				entries = mergeHistoryEntry(entries, e)
+			}
+		}
+		for i := range entries {
+			e := &entries[i]
+			if e.MergedBy == "" && e.MergeCommitSHA != "" && containsEnvironment(envsByURL[gitURL], e.Environment) {
+				author, err := g.Command(dir, "log", "-1", "--format=%an", e.MergeCommitSHA)
+				if err != nil {
+					log.Logger().Debugf("failed to find merge commit %s in %s: %s", e.MergeCommitSHA, gitURL, err.Error())
+					continue
+				}
+				e.MergedBy = author
+			}
+		}
+		done()
+	}
+	return entries, nil
+}
+
+// releaseCommit a commit which changed the release report of an environment git repository
+type releaseCommit struct {
+	SHA       string
+	Author    string
+	Timestamp time.Time
+	Releases  []*releasereport.NamespaceReleases
+
+	// Baseline is true for the commit before the most recent commits which is only used to find the versions they
+	// change
+	Baseline bool
+}
+
+// releaseCommits returns up to maxCommits of the most recent commits which changed the release report, oldest first,
+// along with its contents. If there are older commits the previous commit is included as a baseline
+func releaseCommits(ctx context.Context, g gitclient.Interface, dir string, maxCommits int) ([]releaseCommit, error) {
+	text, err := g.Command(dir, "log", "--reverse", "--max-count="+strconv.Itoa(maxCommits+1), "--format=%H%x1f%an%x1f%cI", "--", ReleasesPath)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	var answer []releaseCommit
+	for i, line := range lines {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		fields := strings.Split(strings.TrimSpace(line), "\x1f")
+		if len(fields) != 3 {
+			continue
+		}
+		c := releaseCommit{SHA: fields[0], Author: fields[1], Baseline: i == 0 && len(lines) > maxCommits}
+		c.Timestamp, err = time.Parse(time.RFC3339, fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the time of commit %s: %w", c.SHA, err)
+		}
+		data, err := g.Command(dir, "show", c.SHA+":"+ReleasesPath)
+		if err != nil {
+			// the release report was removed by this commit
+			answer = append(answer, c)
+			continue
+		}
+		err = yaml.Unmarshal([]byte(data), &c.Releases)
+		if err != nil {
+			log.Logger().Debugf("ignoring invalid %s in commit %s: %s", ReleasesPath, c.SHA, err.Error())
+			continue
+		}
+		answer = append(answer, c)
+	}
+	return answer, nil
+}
+
+// envReleaseChanges returns a promotion for every commit which changed the version of any of the releases in the
+// namespace of the environment
+func envReleaseChanges(commits []releaseCommit, env *v1.Environment, releaseNames []string) []HistoryEntry {
+	var answer []HistoryEntry
+	previous := ""
+	for i := range commits {
+		c := &commits[i]
+		version := ""
+		for _, nr := range c.Releases {
+			if nr == nil || nr.Namespace != env.Spec.Namespace {
+				continue
+			}
+			for _, r := range nr.Releases {
+				if r != nil && r.Version != "" && stringhelpers.StringArrayIndex(releaseNames, r.Name) >= 0 {
+					version = r.Version
+					break
+				}
+			}
+		}
+		if version == previous {
+			continue
+		}
+		previous = version
+		if version == "" || c.Baseline {
+			continue
+		}
+		timestamp := metav1.NewTime(c.Timestamp)
+		answer = append(answer, HistoryEntry{
+			Version:        version,
+			Environment:    env.Name,
+			Timestamp:      &timestamp,
+			MergeCommitSHA: c.SHA,
+			MergedBy:       c.Author,
+			Sources:        []string{HistorySourceGit},
+		})
+	}
+	return answer
+}
+
+// mergeHistoryEntry completes the first promotion of the same version to the same environment which was not found
+// in git with the change to the release report, otherwise adds it
+func mergeHistoryEntry(entries []HistoryEntry, e HistoryEntry) []HistoryEntry {
+	for i := range entries {
+		existing := &entries[i]
+		if existing.Version != e.Version || existing.Environment != e.Environment || stringhelpers.StringArrayIndex(existing.Sources, HistorySourceGit) >= 0 {
+			continue
+		}
+		if existing.MergeCommitSHA == "" {
+			existing.MergeCommitSHA = e.MergeCommitSHA
+		}
+		if existing.MergedBy == "" {
+			existing.MergedBy = e.MergedBy
+		}
+		if existing.Timestamp == nil {
+			existing.Timestamp = e.Timestamp
+		}
+		existing.Sources = append(existing.Sources, HistorySourceGit)
+		return entries
+	}
+	return append(entries, e)
+}
+
+func containsEnvironment(envs []*v1.Environment, name string) bool {
+	for _, env := range envs {
+		if env.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// sortHistory orders the promotions by time then by the promotion order of their environments. Promotions without
+// a time are last
+func sortHistory(entries []HistoryEntry, envs []*v1.Environment) {
+	order := map[string]int{}
+	for i, env := range envs {
+		order[env.Name] = i
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		ti, tj := entries[i].Timestamp, entries[j].Timestamp
+		switch {
+		case ti == nil && tj == nil:
+		case ti == nil:
+			return false
+		case tj == nil:
+			return true
+		case !ti.Equal(tj):
+			return ti.Before(tj)
+		}
+		return order[entries[i].Environment] < order[entries[j].Environment]
+	})
+}
@@ -0,0 +1,146 @@
+package applications
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	v1 "github.com/jenkins-x/jx-api/v4/pkg/apis/jenkins.io/v1"
+	fakejx "github.com/jenkins-x/jx-api/v4/pkg/client/clientset/versioned/fake"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/cli"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestHistory(t *testing.T) {
+	g := cli.NewCLIClient("", cmdrunner.QuietCommandRunner)
+	repoDir := t.TempDir()
+	initTestGitRepository(t, g, repoDir)
+	commitTestNamespaceRelease(t, g, repoDir, "jx-staging", "1.0.0")
+	commitTestNamespaceRelease(t, g, repoDir, "jx-staging", "1.1.0")
+
+	ns := "jx"
+	promoted := metav1.NewTime(time.Now().Add(time.Hour))
+	promoting := metav1.NewTime(time.Now().Add(2 * time.Hour))
+	jxClient := fakejx.NewSimpleClientset(
+		&v1.SourceRepository{
+			ObjectMeta: metav1.ObjectMeta{Name: "myorg-myapp", Namespace: ns},
+			Spec:       v1.SourceRepositorySpec{Org: "myorg", Repo: "myapp"},
+		},
+		&v1.Environment{
+			ObjectMeta: metav1.ObjectMeta{Name: "staging", Namespace: ns},
+			Spec: v1.EnvironmentSpec{
+				Namespace: "jx-staging",
+				Kind:      v1.EnvironmentKindTypePermanent,
+				Order:     100,
+				Source:    v1.EnvironmentRepository{URL: repoDir},
+			},
+		},
+		&v1.Environment{
+			ObjectMeta: metav1.ObjectMeta{Name: "production", Namespace: ns},
+			Spec:       v1.EnvironmentSpec{Namespace: "jx-production", Kind: v1.EnvironmentKindTypePermanent, Order: 200},
+		},
+		newTestPromoteActivity(ns, "myorg", "myapp", "1.1.0", "staging", v1.ActivityStatusTypeSucceeded, &promoted),
+		newTestPromoteActivity(ns, "myorg", "myapp", "1.2.0", "production", v1.ActivityStatusTypeRunning, &promoting),
+		newTestPromoteActivity(ns, "myorg", "other", "9.9.9", "staging", v1.ActivityStatusTypeSucceeded, &promoted),
+	)
+
+	o := &HistoryOptions{JXClient: jxClient, GitClient: g, Namespace: ns}
+	h, err := o.History(context.TODO(), "myapp")
+	require.NoError(t, err)
+	assert.Equal(t, OutputHistoryKind, h.Kind)
+	assert.Equal(t, "myapp", h.Name)
+	require.Len(t, h.Entries, 3)
+
+	e := h.Entries[0]
+	assert.Equal(t, "1.0.0", e.Version)
+	assert.Equal(t, "staging", e.Environment)
+	assert.Equal(t, "test", e.MergedBy)
+	assert.NotEmpty(t, e.MergeCommitSHA)
+	assert.Equal(t, []string{HistorySourceGit}, e.Sources)
+
+	e = h.Entries[1]
+	assert.Equal(t, "1.1.0", e.Version)
+	assert.Equal(t, "staging", e.Environment)
+	assert.Equal(t, string(v1.ActivityStatusTypeSucceeded), e.Status)
+	assert.Equal(t, "https://github.com/myorg/environment-staging/pull/1", e.PullRequestURL)
+	assert.Equal(t, "test", e.MergedBy, "the author of the release report change should be used")
+	assert.True(t, promoted.Equal(e.Timestamp))
+	assert.Equal(t, []string{HistorySourcePipelineActivity, HistorySourceGit}, e.Sources)
+
+	e = h.Entries[2]
+	assert.Equal(t, "1.2.0", e.Version)
+	assert.Equal(t, "production", e.Environment)
+	assert.Equal(t, string(v1.ActivityStatusTypeRunning), e.Status)
+	assert.Empty(t, e.MergedBy)
+
+	o = &HistoryOptions{JXClient: jxClient, Namespace: ns, SkipGit: true, Version: "1.1.0", Environments: []string{"staging"}}
+	h, err = o.History(context.TODO(), "myapp")
+	require.NoError(t, err)
+	require.Len(t, h.Entries, 1)
+	assert.Equal(t, []string{HistorySourcePipelineActivity}, h.Entries[0].Sources)
+
+	_, err = o.History(context.TODO(), "unknown")
+	assert.Error(t, err)
+
+	// only the most recent change should be read with the previous one used to find the version it changed
+	commitTestNamespaceRelease(t, g, repoDir, "jx-staging", "1.3.0")
+	o = &HistoryOptions{JXClient: jxClient, GitClient: g, Namespace: ns, Environments: []string{"staging"}, MaxCommits: 1}
+	h, err = o.History(context.TODO(), "myapp")
+	require.NoError(t, err)
+	require.Len(t, h.Entries, 2, "should not read the change to 1.0.0")
+	assert.Equal(t, "1.3.0", h.Entries[0].Version)
+	assert.Equal(t, []string{HistorySourceGit}, h.Entries[0].Sources)
+	assert.Equal(t, "1.1.0", h.Entries[1].Version)
+	assert.Equal(t, []string{HistorySourcePipelineActivity}, h.Entries[1].Sources, "should only use the change to 1.1.0 as the baseline")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	o.MaxCommits = 0
+	_, err = o.History(ctx, "myapp")
+	assert.Error(t, err, "should stop reading the history once the context is done")
+}
+
+func newTestPromoteActivity(ns, owner, repo, version, env string, status v1.ActivityStatusType, completed *metav1.Time) *v1.PipelineActivity {
+	return &v1.PipelineActivity{
+		ObjectMeta: metav1.ObjectMeta{Name: owner + "-" + repo + "-master-" + version, Namespace: ns},
+		Spec: v1.PipelineActivitySpec{
+			GitOwner:      owner,
+			GitRepository: repo,
+			Version:       version,
+			Steps: []v1.PipelineActivityStep{
+				{
+					Kind: v1.ActivityStepKindTypePromote,
+					Promote: &v1.PromoteActivityStep{
+						CoreActivityStep: v1.CoreActivityStep{Status: status, StartedTimestamp: completed},
+						Environment:      env,
+						PullRequest: &v1.PromotePullRequestStep{
+							CoreActivityStep: v1.CoreActivityStep{CompletedTimestamp: completed},
+							PullRequestURL:   "https://github.com/myorg/environment-" + env + "/pull/1",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func commitTestNamespaceRelease(t *testing.T, g gitclient.Interface, dir, ns, version string) {
+	path := filepath.Join(dir, "docs", "releases.yaml")
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	data := []byte(`- namespace: ` + ns + `
+  releases:
+  - name: myapp
+    version: ` + version + `
+`)
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	_, err := g.Command(dir, "add", ".")
+	require.NoError(t, err)
+	_, err = g.Command(dir, "commit", "-m", "release "+version)
+	require.NoError(t, err)
+}
@@ -0,0 +1,160 @@
+package applications
+
+import (
+	"fmt"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/kube/naming"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// SourceRepositoryAnnotation the annotation on a workload linking it to the name of its SourceRepository
+	SourceRepositoryAnnotation = "jenkins.io/source-repository"
+
+	// NameLabel the recommended kubernetes label for the name of an application
+	NameLabel = "app.kubernetes.io/name"
+
+	// PartOfLabel the recommended kubernetes label for the name of the higher level application a workload is part of
+	PartOfLabel = "app.kubernetes.io/part-of"
+
+	// HelmReleaseNameAnnotation the annotation helm adds to the resources of a release
+	HelmReleaseNameAnnotation = "meta.helm.sh/release-name"
+)
+
+// MatchStrategy how workloads are matched to applications
+type MatchStrategy string
+
+const (
+	// MatchStrategyAuto matches workloads using their labels and annotations, falling back to their name for workloads
+	// which they do not match. The helm release and part-of label are only used for applications which have no
+	// workloads matching by name label or name. A workload with a SourceRepositoryAnnotation only matches that repository
+	MatchStrategyAuto MatchStrategy = "auto"
+
+	// MatchStrategyMetadata matches workloads using only their labels and annotations
+	MatchStrategyMetadata MatchStrategy = "metadata"
+
+	// MatchStrategyName matches workloads using only the application name deduced from their name and app label
+	MatchStrategyName MatchStrategy = "name"
+)
+
+// matchingLabels the labels used to match a workload to an application
+var matchingLabels = []string{NameLabel, PartOfLabel}
+
+// matchingAnnotations the annotations used to match a workload to an application
+var matchingAnnotations = []string{SourceRepositoryAnnotation, HelmReleaseNameAnnotation}
+
+// MatchStrategies the supported match strategies
+var MatchStrategies = []string{string(MatchStrategyAuto), string(MatchStrategyMetadata), string(MatchStrategyName)}
+
+// ParseMatchStrategy parses the match strategy defaulting to MatchStrategyAuto if blank
+func ParseMatchStrategy(text string) (MatchStrategy, error) {
+	switch MatchStrategy(text) {
+	case "":
+		return MatchStrategyAuto, nil
+	case MatchStrategyAuto, MatchStrategyMetadata, MatchStrategyName:
+		return MatchStrategy(text), nil
+	default:
+		return "", fmt.Errorf("unknown match strategy %s, supported values are %v", text, MatchStrategies)
+	}
+}
+
+// matchKind how strongly a workload matches an application
+type matchKind int
+
+const (
+	matchNone matchKind = iota
+
+	// matchWeak the workload is part of the release or higher level application of the application, such as a
+	// subchart, so is only used if the application has no strongly matching workloads
+	matchWeak
+
+	// matchName the application name deduced from the workload name is the application, which is only used if no
+	// application strongly matches the workload
+	matchName
+
+	// matchStrong the workload is named after the application
+	matchStrong
+)
+
+// Matches returns true if the deployment belongs to the application using the strategy
+func (s MatchStrategy) Matches(a *Application, d *Deployment) bool {
+	return s.match(a, d) != matchNone
+}
+
+// match returns how strongly the deployment matches the application using the strategy
+func (s MatchStrategy) match(a *Application, d *Deployment) matchKind {
+	switch s {
+	case MatchStrategyName:
+		if d.Name == a.Name() {
+			return matchStrong
+		}
+		return matchNone
+	case MatchStrategyMetadata:
+		if matchesMetadata(a, d) != matchNone {
+			return matchStrong
+		}
+		return matchNone
+	default:
+		// lets not match by name a workload whose source repository annotation links it to another application
+		if d.Metadata[SourceRepositoryAnnotation] != "" {
+			return matchesMetadata(a, d)
+		}
+		m := matchesMetadata(a, d)
+		if m < matchName && d.Name == a.Name() {
+			return matchName
+		}
+		return m
+	}
+}
+
+// matchesMetadata returns how strongly the labels or annotations of the deployment link it to the application
+func matchesMetadata(a *Application, d *Deployment) matchKind {
+	name := a.Name()
+	if repo := d.Metadata[SourceRepositoryAnnotation]; repo != "" {
+		if repo != a.SourceRepository.Name && repo != a.SourceRepository.Spec.Repo {
+			return matchNone
+		}
+		// a monorepo has several applications so lets check which one this is
+		if a.AppName == "" || d.Metadata[NameLabel] == name || d.Name == name {
+			return matchStrong
+		}
+		return matchNone
+	}
+	if d.Metadata[NameLabel] == name {
+		return matchStrong
+	}
+	if d.Metadata[HelmReleaseNameAnnotation] == name {
+		return matchWeak
+	}
+	partOf := d.Metadata[PartOfLabel]
+	if partOf != "" && (partOf == name || (a.AppName == "" && partOf == naming.ToValidName(a.SourceRepository.Spec.Repo))) {
+		return matchWeak
+	}
+	return matchNone
+}
+
+// matchingMetadata returns the labels and annotations of the workload used for matching or nil if there are none.
+// The app labels of the workload, such as its selector, are used if the workload itself does not have them
+func matchingMetadata(meta *metav1.ObjectMeta, appLabels map[string]string) map[string]string {
+	var answer map[string]string
+	add := func(key, value string) {
+		if value == "" {
+			return
+		}
+		if answer == nil {
+			answer = map[string]string{}
+		}
+		answer[key] = value
+	}
+	for _, k := range matchingLabels {
+		value := meta.Labels[k]
+		if value == "" {
+			value = appLabels[k]
+		}
+		add(k, value)
+	}
+	for _, k := range matchingAnnotations {
+		add(k, meta.Annotations[k])
+	}
+	return answer
+}
@@ -0,0 +1,241 @@
+package applications
+
+import (
+	"testing"
+
+	v1 "github.com/jenkins-x/jx-api/v4/pkg/apis/jenkins.io/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMatchStrategy(t *testing.T) {
+	app := &Application{SourceRepository: &v1.SourceRepository{
+		ObjectMeta: metav1.ObjectMeta{Name: "myorg-myapp"},
+		Spec:       v1.SourceRepositorySpec{Org: "myorg", Repo: "myapp"},
+	}}
+	monorepoApp := &Application{SourceRepository: app.SourceRepository, AppName: "frontend"}
+
+	tests := []struct {
+		name       string
+		app        *Application
+		deployment Deployment
+		auto       bool
+		metadata   bool
+		byName     bool
+	}{
+		{
+			name:       "name only",
+			app:        app,
+			deployment: Deployment{Name: "myapp"},
+			auto:       true,
+			byName:     true,
+		},
+		{
+			name:       "name label",
+			app:        app,
+			deployment: Deployment{Name: "web", Metadata: map[string]string{NameLabel: "myapp"}},
+			auto:       true,
+			metadata:   true,
+		},
+		{
+			name:       "helm release",
+			app:        app,
+			deployment: Deployment{Name: "web", Metadata: map[string]string{HelmReleaseNameAnnotation: "myapp"}},
+			auto:       true,
+			metadata:   true,
+		},
+		{
+			name:       "part of repository",
+			app:        app,
+			deployment: Deployment{Name: "web", Metadata: map[string]string{PartOfLabel: "myapp"}},
+			auto:       true,
+			metadata:   true,
+		},
+		{
+			name:       "monorepo part of repository",
+			app:        monorepoApp,
+			deployment: Deployment{Name: "web", Metadata: map[string]string{PartOfLabel: "myapp"}},
+		},
+		{
+			name:       "name label of another application",
+			app:        app,
+			deployment: Deployment{Name: "myapp", Metadata: map[string]string{NameLabel: "other"}},
+			auto:       true,
+			byName:     true,
+		},
+		{
+			name:       "helm release of another name",
+			app:        app,
+			deployment: Deployment{Name: "myapp", Metadata: map[string]string{HelmReleaseNameAnnotation: "jx-myapp"}},
+			auto:       true,
+			byName:     true,
+		},
+		{
+			name:       "source repository",
+			app:        app,
+			deployment: Deployment{Name: "web", Metadata: map[string]string{SourceRepositoryAnnotation: "myorg-myapp"}},
+			auto:       true,
+			metadata:   true,
+		},
+		{
+			name:       "other source repository",
+			app:        app,
+			deployment: Deployment{Name: "myapp", Metadata: map[string]string{SourceRepositoryAnnotation: "myorg-other"}},
+			byName:     true,
+		},
+		{
+			name:       "monorepo source repository",
+			app:        monorepoApp,
+			deployment: Deployment{Name: "frontend", Metadata: map[string]string{SourceRepositoryAnnotation: "myorg-myapp"}},
+			auto:       true,
+			metadata:   true,
+			byName:     true,
+		},
+		{
+			name:       "monorepo other application",
+			app:        monorepoApp,
+			deployment: Deployment{Name: "backend", Metadata: map[string]string{SourceRepositoryAnnotation: "myorg-myapp"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.auto, MatchStrategyAuto.Matches(tt.app, &tt.deployment), "auto")
+			assert.Equal(t, tt.metadata, MatchStrategyMetadata.Matches(tt.app, &tt.deployment), "metadata")
+			assert.Equal(t, tt.byName, MatchStrategyName.Matches(tt.app, &tt.deployment), "name")
+		})
+	}
+}
+
+func TestNewListWithMatchStrategy(t *testing.T) {
+	env := &v1.Environment{
+		ObjectMeta: metav1.ObjectMeta{Name: "staging"},
+		Spec:       v1.EnvironmentSpec{Namespace: "jx-staging"},
+	}
+	d := newTestDeployment("web", "jx-staging", "1.0.0")
+	d.Annotations = map[string]string{HelmReleaseNameAnnotation: "myapp"}
+	d.Labels[NameLabel] = "myapp"
+	w, err := NewDeploymentWorkload(d)
+	require.NoError(t, err)
+	dep := CreateWorkloadDeployment(w, env)
+	assert.Equal(t, map[string]string{NameLabel: "myapp", HelmReleaseNameAnnotation: "myapp"}, dep.Metadata)
+
+	repositories := []v1.SourceRepository{{
+		ObjectMeta: metav1.ObjectMeta{Name: "myorg-myapp"},
+		Spec:       v1.SourceRepositorySpec{Org: "myorg", Repo: "myapp"},
+	}}
+	envs := map[string]*v1.Environment{"jx-staging": env}
+	deployments := map[string]map[string]Deployment{"jx-staging": {"web": dep}}
+
+	list := NewListWithMatchStrategy(repositories, envs, deployments, MatchStrategyMetadata)
+	require.Len(t, list.Items, 1)
+	require.Len(t, list.Items[0].Environments["staging"].Deployments, 1)
+	assert.Equal(t, "myapp", list.Items[0].Environments["staging"].Deployments[0].Name, "the deployment should be shown with the application name")
+
+	list = NewListWithMatchStrategy(repositories, envs, deployments, MatchStrategyName)
+	assert.Empty(t, list.Items[0].Environments)
+
+	_, err = ParseMatchStrategy("labels")
+	assert.Error(t, err)
+}
+
+func TestNewListWithMatchStrategySubchart(t *testing.T) {
+	env := &v1.Environment{
+		ObjectMeta: metav1.ObjectMeta{Name: "staging"},
+		Spec:       v1.EnvironmentSpec{Namespace: "jx-staging"},
+	}
+	newRepository := func(repo string) v1.SourceRepository {
+		return v1.SourceRepository{
+			ObjectMeta: metav1.ObjectMeta{Name: "myorg-" + repo},
+			Spec:       v1.SourceRepositorySpec{Org: "myorg", Repo: repo},
+		}
+	}
+	repositories := []v1.SourceRepository{newRepository("myapp"), newRepository("cache")}
+	envs := map[string]*v1.Environment{"jx-staging": env}
+	deployments := map[string]map[string]Deployment{"jx-staging": {
+		"myapp": {Name: "myapp", Version: "1.0.0", Metadata: map[string]string{NameLabel: "myapp", HelmReleaseNameAnnotation: "myapp"}},
+		// the database subchart of the myapp release
+		"myapp-postgresql": {Name: "myapp-postgresql", Version: "15.2.0", Metadata: map[string]string{NameLabel: "postgresql", HelmReleaseNameAnnotation: "myapp", PartOfLabel: "myapp"}},
+		// named after myapp but part of the cache application
+		"myapp-worker": {Name: "myapp-worker", Version: "1.0.0", Metadata: map[string]string{NameLabel: "myapp", PartOfLabel: "cache"}},
+		// the cache release has no workload named after it
+		"web":         {Name: "web", Version: "0.3.0", Metadata: map[string]string{HelmReleaseNameAnnotation: "cache"}},
+		"cache-redis": {Name: "cache-redis", Version: "7.0.0", Metadata: map[string]string{NameLabel: "redis", HelmReleaseNameAnnotation: "cache"}},
+	}}
+
+	versions := func(list List, i int) []string {
+		var answer []string
+		for _, d := range list.Items[i].Environments["staging"].Deployments {
+			answer = append(answer, d.Version)
+		}
+		return answer
+	}
+
+	list := NewListWithMatchStrategy(repositories, envs, deployments, MatchStrategyAuto)
+	require.Len(t, list.Items, 2)
+	assert.Equal(t, []string{"1.0.0", "1.0.0"}, versions(list, 0), "should not include the subchart of myapp")
+	assert.Equal(t, []string{"7.0.0", "0.3.0"}, versions(list, 1), "should fall back to the release of cache")
+
+	list = NewListWithMatchStrategy(repositories, envs, deployments, MatchStrategyMetadata)
+	total := 0
+	for i := range list.Items {
+		total += len(list.Items[i].Environments["staging"].Deployments)
+	}
+	assert.Equal(t, len(deployments["jx-staging"]), total, "each workload should be matched to at most one application")
+}
+
+func TestNewListWithMatchStrategyHelmRelease(t *testing.T) {
+	env := &v1.Environment{
+		ObjectMeta: metav1.ObjectMeta{Name: "staging"},
+		Spec:       v1.EnvironmentSpec{Namespace: "jx-staging"},
+	}
+	d := newTestDeployment("myapp", "jx-staging", "1.0.0")
+	d.Annotations = map[string]string{HelmReleaseNameAnnotation: "jx-myapp"}
+	d.Labels[NameLabel] = "myapp-chart"
+	dep, err := CreateDeployment(d, env)
+	require.NoError(t, err)
+
+	repositories := []v1.SourceRepository{{
+		ObjectMeta: metav1.ObjectMeta{Name: "myorg-myapp"},
+		Spec:       v1.SourceRepositorySpec{Org: "myorg", Repo: "myapp"},
+	}}
+	envs := map[string]*v1.Environment{"jx-staging": env}
+	deployments := map[string]map[string]Deployment{"jx-staging": {"myapp": dep}}
+
+	list := NewListWithMatchStrategy(repositories, envs, deployments, MatchStrategyAuto)
+	require.Len(t, list.Items, 1)
+	require.Len(t, list.Items[0].Environments["staging"].Deployments, 1, "should fall back to the workload name when the helm release is named differently")
+	assert.Equal(t, "1.0.0", list.Items[0].Environments["staging"].Deployments[0].Version)
+}
+
+func TestNewListWithMatchStrategyPrefersMetadata(t *testing.T) {
+	env := &v1.Environment{
+		ObjectMeta: metav1.ObjectMeta{Name: "staging"},
+		Spec:       v1.EnvironmentSpec{Namespace: "jx-staging"},
+	}
+	newRepository := func(repo string) v1.SourceRepository {
+		return v1.SourceRepository{
+			ObjectMeta: metav1.ObjectMeta{Name: "myorg-" + repo},
+			Spec:       v1.SourceRepositorySpec{Org: "myorg", Repo: repo},
+		}
+	}
+	envs := map[string]*v1.Environment{"jx-staging": env}
+	deployments := map[string]map[string]Deployment{"jx-staging": {
+		// named after foo but labelled as bar
+		"foo": {Name: "foo", Version: "1.0.0", Metadata: map[string]string{NameLabel: "bar"}},
+	}}
+
+	for _, repositories := range [][]v1.SourceRepository{
+		{newRepository("foo"), newRepository("bar")},
+		{newRepository("bar"), newRepository("foo")},
+	} {
+		list := NewListWithMatchStrategy(repositories, envs, deployments, MatchStrategyAuto)
+		foo := list.Find("foo")
+		bar := list.Find("bar")
+		require.NotNil(t, foo)
+		require.NotNil(t, bar)
+		assert.Empty(t, foo.Environments, "should not match %s by name", repositories[0].Name)
+		require.Len(t, bar.Environments["staging"].Deployments, 1, "should match by the name label when %s is first", repositories[0].Name)
+		assert.Equal(t, "1.0.0", bar.Environments["staging"].Deployments[0].Version)
+	}
+}
@@ -0,0 +1,206 @@
+package applications
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// OutputAPIVersion the version of the machine readable schema used by the json and yaml output formats.
+	// Fields may be added within a version but never renamed or removed
+	OutputAPIVersion = "application.jenkins-x.io/v1"
+
+	// OutputListKind the kind of the machine readable application list
+	OutputListKind = "ApplicationList"
+)
+
+// OutputList is the stable, versioned representation of a List used for machine readable output
+type OutputList struct {
+	APIVersion string              `json:"apiVersion"`
+	Kind       string              `json:"kind"`
+	Items      []OutputApplication `json:"items"`
+	Failures   []OutputFailure     `json:"failures,omitempty"`
+}
+
+// OutputFailure is the stable representation of an environment which could not be fetched
+type OutputFailure struct {
+	Name        string `json:"name"`
+	Namespace   string `json:"namespace,omitempty"`
+	FetchStatus string `json:"fetchStatus"`
+	FetchError  string `json:"fetchError,omitempty"`
+}
+
+// OutputApplication is the stable representation of an Application
+type OutputApplication struct {
+	Name         string              `json:"name"`
+	Owner        string              `json:"owner,omitempty"`
+	Repository   string              `json:"repository,omitempty"`
+	GitURL       string              `json:"gitURL,omitempty"`
+	Environments []OutputEnvironment `json:"environments"`
+}
+
+// OutputEnvironment is the stable representation of an application's Environment
+type OutputEnvironment struct {
+	Name        string             `json:"name"`
+	Namespace   string             `json:"namespace,omitempty"`
+	Kind        string             `json:"kind,omitempty"`
+	Remote      bool               `json:"remote,omitempty"`
+	FetchStatus string             `json:"fetchStatus,omitempty"`
+	FetchError  string             `json:"fetchError,omitempty"`
+	Deployments []OutputDeployment `json:"deployments"`
+}
+
+// OutputDeployment is the stable representation of a workload of an application
+type OutputDeployment struct {
+	Name          string                `json:"name,omitempty"`
+	Kind          string                `json:"kind,omitempty"`
+	Workload      string                `json:"workload,omitempty"`
+	Pods          string                `json:"pods,omitempty"`
+	Version       string                `json:"version,omitempty"`
+	URL           string                `json:"url,omitempty"`
+	Canary        bool                  `json:"canary,omitempty"`
+	VersionSource string                `json:"versionSource,omitempty"`
+	Image         string                `json:"image,omitempty"`
+	Revision      string                `json:"revision,omitempty"`
+	Traffic       []OutputTrafficTarget `json:"traffic,omitempty"`
+	Rollout       *OutputRollout        `json:"rollout,omitempty"`
+	Helm          *OutputHelmRelease    `json:"helm,omitempty"`
+}
+
+// OutputTrafficTarget is the stable representation of the share of the traffic routed to a revision
+type OutputTrafficTarget struct {
+	RevisionName   string `json:"revisionName,omitempty"`
+	Percent        int64  `json:"percent"`
+	Tag            string `json:"tag,omitempty"`
+	LatestRevision bool   `json:"latestRevision,omitempty"`
+}
+
+// OutputRollout is the stable representation of the progressive delivery status of a workload
+type OutputRollout struct {
+	Backend            string `json:"backend"`
+	Phase              string `json:"phase,omitempty"`
+	StableVersion      string `json:"stableVersion,omitempty"`
+	CanaryVersion      string `json:"canaryVersion,omitempty"`
+	Step               string `json:"step,omitempty"`
+	Weight             int64  `json:"weight,omitempty"`
+	FailedChecks       int64  `json:"failedChecks,omitempty"`
+	LastTransitionTime string `json:"lastTransitionTime,omitempty"`
+}
+
+// OutputHelmRelease is the stable representation of the helm release revision which deployed a workload
+type OutputHelmRelease struct {
+	Name         string       `json:"name"`
+	Namespace    string       `json:"namespace,omitempty"`
+	Revision     int          `json:"revision"`
+	Status       string       `json:"status,omitempty"`
+	Chart        string       `json:"chart,omitempty"`
+	ChartVersion string       `json:"chartVersion,omitempty"`
+	AppVersion   string       `json:"appVersion,omitempty"`
+	LastDeployed *metav1.Time `json:"lastDeployed,omitempty"`
+}
+
+// ToOutput converts the list into its versioned output schema including only the given environments in the given order.
+// Applications which are not deployed in any environment are omitted as they are from the table output
+func (l *List) ToOutput(envNames []string) OutputList {
+	answer := OutputList{
+		APIVersion: OutputAPIVersion,
+		Kind:       OutputListKind,
+		Items:      []OutputApplication{},
+	}
+	for i := range l.Items {
+		a := &l.Items[i]
+		if len(a.Environments) == 0 {
+			continue
+		}
+		answer.Items = append(answer.Items, a.ToOutput(envNames))
+	}
+	for i := range l.Failures {
+		env := &l.Failures[i]
+		answer.Failures = append(answer.Failures, OutputFailure{
+			Name:        env.Name,
+			Namespace:   env.Spec.Namespace,
+			FetchStatus: env.FetchStatus,
+			FetchError:  env.FetchError,
+		})
+	}
+	return answer
+}
+
+// ToOutput converts the application into its versioned output schema including only the given environments in the given order
+func (a *Application) ToOutput(envNames []string) OutputApplication {
+	answer := OutputApplication{
+		Name:         a.Name(),
+		Owner:        a.SourceRepository.Spec.Org,
+		Repository:   a.SourceRepository.Spec.Repo,
+		GitURL:       a.SourceRepository.Spec.URL,
+		Environments: []OutputEnvironment{},
+	}
+	for _, name := range envNames {
+		env, ok := a.Environments[name]
+		if !ok {
+			continue
+		}
+		deployments := []OutputDeployment{}
+		for j := range env.Deployments {
+			deployments = append(deployments, env.Deployments[j].ToOutput())
+		}
+		answer.Environments = append(answer.Environments, OutputEnvironment{
+			Name:        name,
+			Namespace:   env.Spec.Namespace,
+			Kind:        string(env.Spec.Kind),
+			Remote:      env.Spec.RemoteCluster,
+			FetchStatus: env.FetchStatus,
+			FetchError:  env.FetchError,
+			Deployments: deployments,
+		})
+	}
+	return answer
+}
+
+// ToOutput converts the deployment into its versioned output schema
+func (d *Deployment) ToOutput() OutputDeployment {
+	answer := OutputDeployment{
+		Name:          d.Name,
+		Kind:          d.Kind,
+		Workload:      d.Workload,
+		Pods:          d.Pods,
+		Version:       d.Version,
+		URL:           d.URL,
+		Canary:        d.Canary,
+		VersionSource: d.VersionSource,
+		Image:         d.Image,
+		Revision:      d.Revision,
+	}
+	for _, t := range d.Traffic {
+		answer.Traffic = append(answer.Traffic, OutputTrafficTarget{
+			RevisionName:   t.RevisionName,
+			Percent:        t.Percent,
+			Tag:            t.Tag,
+			LatestRevision: t.LatestRevision,
+		})
+	}
+	if r := d.Rollout; r != nil {
+		answer.Rollout = &OutputRollout{
+			Backend:            r.Backend,
+			Phase:              r.Phase,
+			StableVersion:      r.StableVersion,
+			CanaryVersion:      r.CanaryVersion,
+			Step:               r.Step,
+			Weight:             r.Weight,
+			FailedChecks:       r.FailedChecks,
+			LastTransitionTime: r.LastTransitionTime,
+		}
+	}
+	if h := d.Helm; h != nil {
+		answer.Helm = &OutputHelmRelease{
+			Name:         h.Name,
+			Namespace:    h.Namespace,
+			Revision:     h.Revision,
+			Status:       h.Status,
+			Chart:        h.Chart,
+			ChartVersion: h.ChartVersion,
+			AppVersion:   h.AppVersion,
+			LastDeployed: h.LastDeployed,
+		}
+	}
+	return answer
+}
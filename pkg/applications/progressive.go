@@ -0,0 +1,350 @@
+package applications
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// KindRollout the kind of an Argo Rollouts Rollout workload
+	KindRollout = "Rollout"
+
+	// BackendFlagger the name of the Flagger progressive delivery backend
+	BackendFlagger = "Flagger"
+
+	// BackendArgoRollouts the name of the Argo Rollouts progressive delivery backend
+	BackendArgoRollouts = "Argo"
+
+	// rolloutsPodTemplateHashLabel the label Argo Rollouts adds to the ReplicaSets of each revision of a Rollout
+	rolloutsPodTemplateHashLabel = "rollouts-pod-template-hash"
+)
+
+// ProgressiveDeliveryBackendNames the names of the supported progressive delivery backends
+var ProgressiveDeliveryBackendNames = []string{strings.ToLower(BackendFlagger), strings.ToLower(BackendArgoRollouts)}
+
+// CanaryResource the resource of Flagger Canaries
+var CanaryResource = schema.GroupVersionResource{Group: "flagger.app", Version: "v1beta1", Resource: "canaries"}
+
+// RolloutResource the resource of Argo Rollouts
+var RolloutResource = schema.GroupVersionResource{Group: "argoproj.io", Version: "v1alpha1", Resource: "rollouts"}
+
+// ProgressiveDeliveryBackend is a progressive delivery tool such as Flagger or Argo Rollouts which manages how new
+// versions of workloads are rolled out
+type ProgressiveDeliveryBackend interface {
+	// Name returns the name of the backend
+	Name() string
+
+	// IsAuxiliary returns true if the resource was created by the backend and should not be shown as an application
+	IsAuxiliary(meta *metav1.ObjectMeta) bool
+
+	// Resources returns the custom resources used by the backend
+	Resources() []schema.GroupVersionResource
+
+	// Workloads updates the workloads of a namespace from the custom resources of the backend in the namespace,
+	// returning any additional workloads which are only defined by those resources
+	Workloads(ctx context.Context, replicaSets ReplicaSetListFunc, resources []unstructured.Unstructured, workloads []*Workload) ([]*Workload, error)
+}
+
+// ReplicaSetListFunc lists the ReplicaSets in a namespace matching the selector
+type ReplicaSetListFunc func(ctx context.Context, ns string, selector labels.Selector) ([]*appsv1.ReplicaSet, error)
+
+// ClientReplicaSets returns a ReplicaSetListFunc which lists the ReplicaSets using the kube client or nil if the
+// client is nil
+func ClientReplicaSets(kubeClient kubernetes.Interface) ReplicaSetListFunc {
+	if kubeClient == nil {
+		return nil
+	}
+	return func(ctx context.Context, ns string, selector labels.Selector) ([]*appsv1.ReplicaSet, error) {
+		list, err := kubeClient.AppsV1().ReplicaSets(ns).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+		if err != nil {
+			return nil, err
+		}
+		answer := make([]*appsv1.ReplicaSet, 0, len(list.Items))
+		for i := range list.Items {
+			answer = append(answer, &list.Items[i])
+		}
+		return answer, nil
+	}
+}
+
+// DefaultProgressiveDeliveryBackends returns the progressive delivery backends used if none are configured
+func DefaultProgressiveDeliveryBackends() []ProgressiveDeliveryBackend {
+	return []ProgressiveDeliveryBackend{&FlaggerBackend{}, &ArgoRolloutsBackend{}}
+}
+
+// NewProgressiveDeliveryBackends returns the progressive delivery backends with the given names
+func NewProgressiveDeliveryBackends(names []string) ([]ProgressiveDeliveryBackend, error) {
+	answer := []ProgressiveDeliveryBackend{}
+	for _, name := range names {
+		switch strings.ToLower(name) {
+		case strings.ToLower(BackendFlagger):
+			answer = append(answer, &FlaggerBackend{})
+		case strings.ToLower(BackendArgoRollouts):
+			answer = append(answer, &ArgoRolloutsBackend{})
+		default:
+			return nil, fmt.Errorf("unknown progressive delivery backend %s, supported values are %v", name, ProgressiveDeliveryBackendNames)
+		}
+	}
+	return answer, nil
+}
+
+// listResourcesFunc lists the custom resources of the given type in a namespace
+type listResourcesFunc func(resource schema.GroupVersionResource) ([]unstructured.Unstructured, error)
+
+// applyProgressiveDelivery marks the auxiliary workloads of the backends and returns the workloads along with any
+// additional workloads of the backends
+func applyProgressiveDelivery(ctx context.Context, replicaSets ReplicaSetListFunc, backends []ProgressiveDeliveryBackend, workloads []*Workload, list listResourcesFunc) ([]*Workload, error) {
+	for _, w := range workloads {
+		w.Auxiliary = isAuxiliary(backends, w.ObjectMeta)
+	}
+	answer := workloads
+	for _, b := range backends {
+		var resources []unstructured.Unstructured
+		for _, r := range b.Resources() {
+			items, err := list(r)
+			if err != nil {
+				return nil, err
+			}
+			resources = append(resources, items...)
+		}
+		extra, err := b.Workloads(ctx, replicaSets, resources, workloads)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find %s workloads: %w", b.Name(), err)
+		}
+		answer = append(answer, extra...)
+	}
+	return answer, nil
+}
+
+// isAuxiliary returns true if any of the backends created the resource
+func isAuxiliary(backends []ProgressiveDeliveryBackend, meta *metav1.ObjectMeta) bool {
+	for _, b := range backends {
+		if b.IsAuxiliary(meta) {
+			return true
+		}
+	}
+	return false
+}
+
+// progressiveDeliveryResources returns the custom resources of all the backends
+func progressiveDeliveryResources(backends []ProgressiveDeliveryBackend) []schema.GroupVersionResource {
+	var answer []schema.GroupVersionResource
+	for _, b := range backends {
+		answer = append(answer, b.Resources()...)
+	}
+	return answer
+}
+
+// FlaggerBackend supports Flagger canaries which create a primary Deployment from a target Deployment and shift
+// traffic to the target while analysing each new version
+type FlaggerBackend struct{}
+
+// Name returns the name of the backend
+func (b *FlaggerBackend) Name() string {
+	return BackendFlagger
+}
+
+// IsAuxiliary returns whether this workload has been created automatically by Flagger from a Canary object
+func (b *FlaggerBackend) IsAuxiliary(meta *metav1.ObjectMeta) bool {
+	for i := range meta.OwnerReferences {
+		if meta.OwnerReferences[i].Kind == "Canary" {
+			return true
+		}
+	}
+	return false
+}
+
+// Resources returns the custom resources used by the backend
+func (b *FlaggerBackend) Resources() []schema.GroupVersionResource {
+	return []schema.GroupVersionResource{CanaryResource}
+}
+
+// Workloads attaches the status of each Canary to the workload it targets. The pods of the primary are used if the
+// target has been scaled down between rollouts
+func (b *FlaggerBackend) Workloads(_ context.Context, _ ReplicaSetListFunc, resources []unstructured.Unstructured, workloads []*Workload) ([]*Workload, error) {
+	for i := range resources {
+		u := &resources[i]
+		kind, _, _ := unstructured.NestedString(u.Object, "spec", "targetRef", "kind")
+		name, _, _ := unstructured.NestedString(u.Object, "spec", "targetRef", "name")
+		if kind == "" {
+			kind = KindDeployment
+		}
+		target := findWorkload(workloads, kind, name)
+		if target == nil {
+			continue
+		}
+		primary := findWorkload(workloads, kind, name+"-primary")
+
+		status := &RolloutStatus{Backend: BackendFlagger}
+		status.Phase, _, _ = unstructured.NestedString(u.Object, "status", "phase")
+		status.Weight, _, _ = unstructured.NestedInt64(u.Object, "status", "canaryWeight")
+		status.FailedChecks, _, _ = unstructured.NestedInt64(u.Object, "status", "failedChecks")
+		status.LastTransitionTime, _, _ = unstructured.NestedString(u.Object, "status", "lastTransitionTime")
+		if primary != nil {
+			status.StableVersion = getVersion(primary.ObjectMeta)
+			if target.Pods == "" {
+				target.Pods = primary.Pods
+			}
+		}
+		if isCanaryInProgress(status.Phase) {
+			status.CanaryVersion = getVersion(target.ObjectMeta)
+		}
+		target.Rollout = status
+	}
+	return nil, nil
+}
+
+// isCanaryInProgress returns true if the Flagger canary phase means a new version is being analysed or promoted
+func isCanaryInProgress(phase string) bool {
+	switch phase {
+	case "Progressing", "Promoting", "Finalising", "Waiting", "WaitingPromotion":
+		return true
+	}
+	return false
+}
+
+// findWorkload returns the workload of the given kind and name or nil if there is not one
+func findWorkload(workloads []*Workload, kind, name string) *Workload {
+	for _, w := range workloads {
+		if w.Kind == kind && w.ObjectMeta.Name == name {
+			return w
+		}
+	}
+	return nil
+}
+
+// ArgoRolloutsBackend supports Argo Rollouts which replace Deployments with Rollouts owning the ReplicaSets
+type ArgoRolloutsBackend struct{}
+
+// Name returns the name of the backend
+func (b *ArgoRolloutsBackend) Name() string {
+	return BackendArgoRollouts
+}
+
+// IsAuxiliary returns false as Rollouts own ReplicaSets rather than Deployments
+func (b *ArgoRolloutsBackend) IsAuxiliary(*metav1.ObjectMeta) bool {
+	return false
+}
+
+// Resources returns the custom resources used by the backend
+func (b *ArgoRolloutsBackend) Resources() []schema.GroupVersionResource {
+	return []schema.GroupVersionResource{RolloutResource}
+}
+
+// Workloads returns a workload for each Rollout
+func (b *ArgoRolloutsBackend) Workloads(ctx context.Context, replicaSets ReplicaSetListFunc, resources []unstructured.Unstructured, _ []*Workload) ([]*Workload, error) {
+	var answer []*Workload
+	for i := range resources {
+		w, err := NewRolloutWorkload(ctx, replicaSets, &resources[i])
+		if err != nil {
+			return nil, err
+		}
+		answer = append(answer, w)
+	}
+	return answer, nil
+}
+
+// NewRolloutWorkload creates a workload from an Argo Rollout. The stable version is found from the stable ReplicaSet
+// if the ReplicaSets can be listed
+func NewRolloutWorkload(ctx context.Context, replicaSets ReplicaSetListFunc, u *unstructured.Unstructured) (*Workload, error) {
+	meta, err := objectMeta(u)
+	if err != nil {
+		return nil, err
+	}
+	selector, _, _ := unstructured.NestedStringMap(u.Object, "spec", "selector", "matchLabels")
+	templateLabels, _, _ := unstructured.NestedStringMap(u.Object, "spec", "template", "metadata", "labels")
+	replicas, found, _ := unstructured.NestedInt64(u.Object, "spec", "replicas")
+	if !found {
+		replicas = 1
+	}
+	ready, _, _ := unstructured.NestedInt64(u.Object, "status", "readyReplicas")
+	desired := int32(replicas) // #nosec G115
+
+	status := &RolloutStatus{Backend: BackendArgoRollouts}
+	status.Phase, _, _ = unstructured.NestedString(u.Object, "status", "phase")
+	stableHash, _, _ := unstructured.NestedString(u.Object, "status", "stableRS")
+	currentHash, _, _ := unstructured.NestedString(u.Object, "status", "currentPodHash")
+	version, versionSource := versionFromLabels(templateLabels)
+	status.StableVersion = version
+	if stableHash != "" && currentHash != "" && stableHash != currentHash {
+		status.CanaryVersion = version
+		status.StableVersion = replicaSetVersion(ctx, replicaSets, meta.Namespace, stableHash)
+		status.Step, status.Weight = rolloutStep(u)
+	}
+
+	w := &Workload{
+		Kind:       KindRollout,
+		ObjectMeta: meta,
+		AppLabels:  selector,
+		Pods:       readyPods(int32(ready), &desired), // #nosec G115
+		Version:    status.StableVersion,
+		Rollout:    status,
+		Image:      templateImage(u),
+	}
+	if w.Version == "" {
+		w.Version = version
+	}
+	if w.Version != "" {
+		w.VersionSource = versionSource
+	}
+	return w, nil
+}
+
+// rolloutStep returns the current step out of the total steps of a canary Rollout and the weight of the canary
+func rolloutStep(u *unstructured.Unstructured) (string, int64) {
+	steps, _, _ := unstructured.NestedSlice(u.Object, "spec", "strategy", "canary", "steps")
+	index, found, _ := unstructured.NestedInt64(u.Object, "status", "currentStepIndex")
+	step := ""
+	if found && len(steps) > 0 {
+		step = strconv.FormatInt(index, 10) + "/" + strconv.Itoa(len(steps))
+	}
+
+	weight, found, _ := unstructured.NestedInt64(u.Object, "status", "canary", "weights", "canary", "weight")
+	if found {
+		return step, weight
+	}
+	// older versions of Argo Rollouts do not report the weight so lets use the last weight set by the steps
+	for i := 0; i < len(steps) && int64(i) < index; i++ {
+		s, ok := steps[i].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		w, found, _ := unstructured.NestedInt64(s, "setWeight")
+		if found {
+			weight = w
+		}
+	}
+	return step, weight
+}
+
+// replicaSetVersion returns the version of the Rollout ReplicaSet with the given pod template hash
+func replicaSetVersion(ctx context.Context, replicaSets ReplicaSetListFunc, ns, hash string) string {
+	if replicaSets == nil {
+		return ""
+	}
+	list, err := replicaSets(ctx, ns, labels.SelectorFromSet(labels.Set{rolloutsPodTemplateHashLabel: hash}))
+	if err != nil {
+		log.Logger().Debugf("failed to find the ReplicaSets with hash %s in namespace %s: %s", hash, ns, err.Error())
+		return ""
+	}
+	for _, rs := range list {
+		version := getVersion(&rs.Spec.Template.ObjectMeta)
+		if version == "" {
+			version = getVersion(&rs.ObjectMeta)
+		}
+		if version != "" {
+			return version
+		}
+	}
+	return ""
+}
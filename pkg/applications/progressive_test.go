@@ -0,0 +1,151 @@
+package applications
+
+import (
+	"context"
+	"testing"
+
+	v1 "github.com/jenkins-x/jx-api/v4/pkg/apis/jenkins.io/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakedyn "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestGetDeploymentsProgressiveDelivery(t *testing.T) {
+	ns := "jx-production"
+	primary := newTestDeployment("myapp-primary", ns, "1.0.0")
+	primary.OwnerReferences = []metav1.OwnerReference{{APIVersion: "flagger.app/v1beta1", Kind: "Canary", Name: "myapp"}}
+	stable := &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "rollout-app-abc",
+			Namespace: ns,
+			Labels:    map[string]string{rolloutsPodTemplateHashLabel: "abc"},
+		},
+		Spec: appsv1.ReplicaSetSpec{
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"version": "2.0.0"}},
+			},
+		},
+	}
+	primary.Status.ReadyReplicas = 2
+	primary.Spec.Replicas = &[]int32{2}[0]
+	kubeClient := fake.NewSimpleClientset(newTestDeployment("myapp", ns, "1.1.0"), primary, stable)
+
+	canary := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "flagger.app/v1beta1",
+		"kind":       "Canary",
+		"metadata": map[string]interface{}{
+			"name":      "myapp",
+			"namespace": ns,
+		},
+		"spec": map[string]interface{}{
+			"targetRef": map[string]interface{}{"apiVersion": "apps/v1", "kind": "Deployment", "name": "myapp"},
+		},
+		"status": map[string]interface{}{
+			"phase":              "Progressing",
+			"canaryWeight":       int64(30),
+			"failedChecks":       int64(1),
+			"lastTransitionTime": "2024-05-01T10:00:00Z",
+		},
+	}}
+
+	rollout := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "argoproj.io/v1alpha1",
+		"kind":       "Rollout",
+		"metadata": map[string]interface{}{
+			"name":      "rollout-app",
+			"namespace": ns,
+		},
+		"spec": map[string]interface{}{
+			"replicas": int64(4),
+			"selector": map[string]interface{}{
+				"matchLabels": map[string]interface{}{"app": "rollout-app"},
+			},
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"labels": map[string]interface{}{"app": "rollout-app", "version": "2.1.0"},
+				},
+			},
+			"strategy": map[string]interface{}{
+				"canary": map[string]interface{}{
+					"steps": []interface{}{
+						map[string]interface{}{"setWeight": int64(20)},
+						map[string]interface{}{"pause": map[string]interface{}{}},
+						map[string]interface{}{"setWeight": int64(50)},
+						map[string]interface{}{"pause": map[string]interface{}{}},
+					},
+				},
+			},
+		},
+		"status": map[string]interface{}{
+			"phase":            "Paused",
+			"readyReplicas":    int64(4),
+			"stableRS":         "abc",
+			"currentPodHash":   "def",
+			"currentStepIndex": int64(1),
+		},
+	}}
+	dynamicClient := fakedyn.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		KnativeServiceResource:   "ServiceList",
+		InferenceServiceResource: "InferenceServiceList",
+		RolloutResource:          "RolloutList",
+		CanaryResource:           "CanaryList",
+	}, rollout, canary)
+	env := &v1.Environment{
+		ObjectMeta: metav1.ObjectMeta{Name: "production"},
+		Spec:       v1.EnvironmentSpec{Namespace: ns},
+	}
+
+	deployments, err := getDeployments(context.TODO(), kubeClient, dynamicClient, DefaultProgressiveDeliveryBackends(), ns, env, "")
+	require.NoError(t, err)
+	assert.True(t, deployments["myapp-primary"].Canary, "the Flagger primary should be hidden")
+	assert.Equal(t, Deployment{
+		Name:          "myapp",
+		Kind:          KindDeployment,
+		Workload:      "myapp",
+		Pods:          "2/2",
+		Version:       "1.1.0",
+		VersionSource: LabelVersionSource("version"),
+		Rollout: &RolloutStatus{
+			Backend:            BackendFlagger,
+			Phase:              "Progressing",
+			StableVersion:      "1.0.0",
+			CanaryVersion:      "1.1.0",
+			Weight:             30,
+			FailedChecks:       1,
+			LastTransitionTime: "2024-05-01T10:00:00Z",
+		},
+	}, deployments["myapp"])
+	assert.Equal(t, Deployment{
+		Name:          "rollout-app",
+		Kind:          KindRollout,
+		Workload:      "rollout-app",
+		Pods:          "4/4",
+		Version:       "2.0.0",
+		VersionSource: LabelVersionSource("version"),
+		Rollout: &RolloutStatus{
+			Backend:       BackendArgoRollouts,
+			Phase:         "Paused",
+			StableVersion: "2.0.0",
+			CanaryVersion: "2.1.0",
+			Step:          "1/4",
+			Weight:        20,
+		},
+	}, deployments["Rollout/rollout-app"])
+
+	// without the Argo backend the rollout is ignored
+	backends, err := NewProgressiveDeliveryBackends([]string{"flagger"})
+	require.NoError(t, err)
+	deployments, err = getDeployments(context.TODO(), kubeClient, dynamicClient, backends, ns, env, "")
+	require.NoError(t, err)
+	assert.NotContains(t, deployments, "Rollout/rollout-app")
+
+	_, err = NewProgressiveDeliveryBackends([]string{"spinnaker"})
+	assert.Error(t, err)
+}
@@ -1,42 +1,48 @@
 package applications
 
 import (
+	"context"
 	"fmt"
-	"path/filepath"
 
 	"github.com/jenkins-x-plugins/jx-gitops/pkg/releasereport"
 	v1 "github.com/jenkins-x/jx-api/v4/pkg/apis/jenkins.io/v1"
-	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient"
+	"sigs.k8s.io/yaml"
 )
 
-// GetRemoteDeployments finds the remote cluster's
+// GetRemoteDeployments finds the remote cluster's deployments by cloning its git repository to a temporary directory
 func GetRemoteDeployments(g gitclient.Interface, env *v1.Environment) (map[string]Deployment, error) {
+	return GetRemoteDeploymentsWithCache(g, env, nil)
+}
+
+// GetRemoteDeploymentsWithCache finds the remote cluster's deployments reusing the clone of its git repository from
+// the cache if it is not nil
+func GetRemoteDeploymentsWithCache(g gitclient.Interface, env *v1.Environment, cache *RemoteCache) (map[string]Deployment, error) {
+	return GetRemoteDeploymentsWithFetcher(context.TODO(), &CloneReleaseFetcher{GitClient: g, Cache: cache}, env)
+}
+
+// GetRemoteDeploymentsWithFetcher finds the remote cluster's deployments from the release report of its git
+// repository fetched by the given fetcher
+func GetRemoteDeploymentsWithFetcher(ctx context.Context, fetcher ReleaseFetcher, env *v1.Environment) (map[string]Deployment, error) {
 	gitURL := env.Spec.Source.URL
 
 	if gitURL == "" {
 		return nil, fmt.Errorf("no git URL on environment %s", env.Name)
 	}
 
-	dir, err := gitclient.CloneToDir(g, gitURL, "")
+	data, err := fetcher.FetchReleases(ctx, gitURL)
 	if err != nil {
-		return nil, fmt.Errorf("failed to clone git URL %s for environment %s: %w", gitURL, env.Name, err)
+		return nil, fmt.Errorf("failed to fetch releases for environment %s: %w", env.Name, err)
 	}
-
-	path := filepath.Join(dir, "docs", "releases.yaml")
-	exists, err := files.FileExists(path)
-	if err != nil {
-		return nil, fmt.Errorf("failed to check for file %s in git clone of %s: %w", path, gitURL, err)
-	}
-	if !exists {
+	if len(data) == 0 {
 		return nil, nil
 	}
 
 	var releases []*releasereport.NamespaceReleases
 
-	err = releasereport.LoadReleases(path, &releases)
+	err = yaml.Unmarshal(data, &releases)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to unmarshal %s of %s: %w", ReleasesPath, gitURL, err)
 	}
 
 	ns := env.Spec.Namespace
@@ -46,7 +52,6 @@ func GetRemoteDeployments(g gitclient.Interface, env *v1.Environment) (map[strin
 		}
 	}
 	return nil, nil
-
 }
 
 func ToDeploymentMap(releases []*releasereport.ReleaseInfo) map[string]Deployment {
@@ -0,0 +1,133 @@
+package applications
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/homedir"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+)
+
+// lastFetchFile the file inside the .git directory of a cached clone whose modification time records the last fetch
+const lastFetchFile = "jx-application-last-fetch"
+
+// RemoteCache caches the git clones of remote environment repositories between invocations
+type RemoteCache struct {
+	// Dir the directory containing a clone per git URL
+	Dir string
+
+	// TTL if non zero a clone fetched more recently than this is used without fetching
+	TTL time.Duration
+}
+
+// DefaultRemoteCacheDir returns the default directory to cache remote environment clones inside the jx home
+func DefaultRemoteCacheDir() (string, error) {
+	dir, err := homedir.CacheDir(os.Getenv("JX3_HOME"), ".jx3")
+	if err != nil {
+		return "", fmt.Errorf("failed to find the jx cache dir: %w", err)
+	}
+	return filepath.Join(dir, "applications", "remote"), nil
+}
+
+// Clone returns a directory containing an up to date clone of the git URL along with a function which must be
+// invoked once the clone is no longer used. If the cache is nil the clone is made in a temporary directory which
+// is removed by the function.
+//
+// Cached clones are guarded by a lock file next to the clone so that concurrent fetches, whether from the same
+// process or from other commands sharing the cache directory, do not update the same clone at once
+func (c *RemoteCache) Clone(g gitclient.Interface, gitURL string) (string, func(), error) {
+	if c == nil || c.Dir == "" {
+		dir, err := gitclient.CloneToDir(g, gitURL, "")
+		if err != nil {
+			return "", nil, err
+		}
+		return dir, func() {
+			err := os.RemoveAll(dir)
+			if err != nil {
+				log.Logger().Debugf("failed to remove temporary clone %s: %s", dir, err.Error())
+			}
+		}, nil
+	}
+
+	err := os.MkdirAll(c.Dir, files.DefaultDirWritePermissions)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create cache dir %s: %w", c.Dir, err)
+	}
+	dir := filepath.Join(c.Dir, cacheKey(gitURL))
+	unlock, err := lockFile(dir + ".lock")
+	if err != nil {
+		return "", nil, err
+	}
+
+	err = c.update(g, gitURL, dir)
+	if err != nil {
+		unlock()
+		return "", nil, err
+	}
+	return dir, unlock, nil
+}
+
+// update ensures the cached clone exists and has been fetched within the TTL
+func (c *RemoteCache) update(g gitclient.Interface, gitURL, dir string) error {
+	stamp := filepath.Join(dir, ".git", lastFetchFile)
+	exists, err := files.DirExists(filepath.Join(dir, ".git"))
+	if err != nil {
+		return fmt.Errorf("failed to check for cached clone %s: %w", dir, err)
+	}
+	if exists {
+		if c.TTL > 0 {
+			info, err := os.Stat(stamp)
+			if err == nil && time.Since(info.ModTime()) < c.TTL {
+				log.Logger().Debugf("using cached clone of %s fetched at %s", gitURL, info.ModTime().Format(time.RFC3339))
+				return nil
+			}
+		}
+		err = fetchAndReset(g, dir)
+		if err == nil {
+			return touch(stamp)
+		}
+		log.Logger().Debugf("failed to update cached clone of %s so recloning: %s", gitURL, err.Error())
+		err = os.RemoveAll(dir)
+		if err != nil {
+			return fmt.Errorf("failed to remove cached clone %s: %w", dir, err)
+		}
+	}
+
+	_, err = gitclient.CloneToDir(g, gitURL, dir)
+	if err != nil {
+		// lets not leave a partial clone behind
+		_ = os.RemoveAll(dir)
+		return err
+	}
+	return touch(stamp)
+}
+
+// fetchAndReset updates the clone to the latest commit of the default branch of the remote
+func fetchAndReset(g gitclient.Interface, dir string) error {
+	_, err := g.Command(dir, "fetch", "origin", "HEAD")
+	if err != nil {
+		return err
+	}
+	_, err = g.Command(dir, "reset", "--hard", "FETCH_HEAD")
+	return err
+}
+
+func touch(path string) error {
+	err := os.WriteFile(path, []byte(time.Now().Format(time.RFC3339)), files.DefaultFileWritePermissions)
+	if err != nil {
+		return fmt.Errorf("failed to save %s: %w", path, err)
+	}
+	return nil
+}
+
+// cacheKey returns the directory name of the clone for the git URL. The URL is hashed as it may contain credentials
+func cacheKey(gitURL string) string {
+	sum := sha256.Sum256([]byte(gitURL))
+	return hex.EncodeToString(sum[:])[0:16]
+}
@@ -0,0 +1,36 @@
+//go:build !windows
+
+package applications
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"golang.org/x/sys/unix"
+)
+
+// lockFile blocks until it holds an exclusive lock on the file, creating it if required, returning a function which
+// releases the lock. The lock is released by the operating system if the process exits
+func lockFile(path string) (func(), error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, files.DefaultFileWritePermissions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file %s: %w", path, err)
+	}
+	fd := int(f.Fd())
+	for {
+		err = unix.Flock(fd, unix.LOCK_EX)
+		if !errors.Is(err, unix.EINTR) {
+			break
+		}
+	}
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+	return func() {
+		_ = unix.Flock(fd, unix.LOCK_UN)
+		_ = f.Close()
+	}, nil
+}
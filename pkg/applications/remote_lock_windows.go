@@ -0,0 +1,30 @@
+//go:build windows
+
+package applications
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"golang.org/x/sys/windows"
+)
+
+// lockFile blocks until it holds an exclusive lock on the file, creating it if required, returning a function which
+// releases the lock. The lock is released by the operating system if the process exits
+func lockFile(path string) (func(), error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, files.DefaultFileWritePermissions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file %s: %w", path, err)
+	}
+	h := windows.Handle(f.Fd())
+	err = windows.LockFileEx(h, windows.LOCKFILE_EXCLUSIVE_LOCK, 0, 1, 0, &windows.Overlapped{})
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+	return func() {
+		_ = windows.UnlockFileEx(h, 0, 1, 0, &windows.Overlapped{})
+		_ = f.Close()
+	}, nil
+}
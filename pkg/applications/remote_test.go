@@ -0,0 +1,119 @@
+package applications
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	v1 "github.com/jenkins-x/jx-api/v4/pkg/apis/jenkins.io/v1"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/cli"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetRemoteDeploymentsWithCache(t *testing.T) {
+	g := cli.NewCLIClient("", cmdrunner.QuietCommandRunner)
+	repoDir := t.TempDir()
+	initTestGitRepository(t, g, repoDir)
+	commitTestRelease(t, g, repoDir, "1.0.0")
+
+	env := &v1.Environment{
+		ObjectMeta: metav1.ObjectMeta{Name: "production"},
+		Spec: v1.EnvironmentSpec{
+			Namespace:     "jx-production",
+			RemoteCluster: true,
+			Source:        v1.EnvironmentRepository{URL: repoDir},
+		},
+	}
+	cache := &RemoteCache{Dir: t.TempDir()}
+
+	deployments, err := GetRemoteDeploymentsWithCache(g, env, cache)
+	require.NoError(t, err)
+	assert.Equal(t, "1.0.0", deployments["myapp"].Version)
+	assert.DirExists(t, filepath.Join(cache.Dir, cacheKey(repoDir), ".git"), "should have cached the clone")
+
+	// the cached clone should be fetched and reset to the latest commit
+	commitTestRelease(t, g, repoDir, "1.1.0")
+	deployments, err = GetRemoteDeploymentsWithCache(g, env, cache)
+	require.NoError(t, err)
+	assert.Equal(t, "1.1.0", deployments["myapp"].Version)
+
+	// within the TTL the cached clone should not be fetched
+	cache.TTL = time.Hour
+	commitTestRelease(t, g, repoDir, "1.2.0")
+	deployments, err = GetRemoteDeploymentsWithCache(g, env, cache)
+	require.NoError(t, err)
+	assert.Equal(t, "1.1.0", deployments["myapp"].Version)
+
+	// without a cache we should always see the latest commit
+	deployments, err = GetRemoteDeploymentsWithCache(g, env, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "1.2.0", deployments["myapp"].Version)
+}
+
+func TestRemoteCacheLock(t *testing.T) {
+	g := cli.NewCLIClient("", cmdrunner.QuietCommandRunner)
+	repoDir := t.TempDir()
+	initTestGitRepository(t, g, repoDir)
+	commitTestRelease(t, g, repoDir, "1.0.0")
+	cache := &RemoteCache{Dir: t.TempDir()}
+
+	// lets hold the lock file as another process sharing the cache directory would
+	unlock, err := lockFile(filepath.Join(cache.Dir, cacheKey(repoDir)+".lock"))
+	require.NoError(t, err)
+
+	done := make(chan error, 1)
+	go func() {
+		_, release, err := cache.Clone(g, repoDir)
+		if err == nil {
+			release()
+		}
+		done <- err
+	}()
+
+	select {
+	case <-done:
+		require.Fail(t, "should wait for the lock on the cached clone")
+	case <-time.After(200 * time.Millisecond):
+	}
+	unlock()
+
+	select {
+	case err = <-done:
+		require.NoError(t, err)
+	case <-time.After(10 * time.Second):
+		require.Fail(t, "timed out waiting for the cached clone")
+	}
+	assert.DirExists(t, filepath.Join(cache.Dir, cacheKey(repoDir), ".git"))
+}
+
+func initTestGitRepository(t *testing.T, g gitclient.Interface, dir string) {
+	_, err := g.Command(dir, "init")
+	require.NoError(t, err)
+	_, err = g.Command(dir, "config", "user.email", "test@example.com")
+	require.NoError(t, err)
+	_, err = g.Command(dir, "config", "user.name", "test")
+	require.NoError(t, err)
+	_, err = g.Command(dir, "config", "commit.gpgsign", "false")
+	require.NoError(t, err)
+}
+
+func commitTestRelease(t *testing.T, g gitclient.Interface, dir, version string) {
+	path := filepath.Join(dir, "docs", "releases.yaml")
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	data := []byte(`- namespace: jx-production
+  releases:
+  - name: myapp
+    version: ` + version + `
+    applicationUrl: https://myapp.example.com
+`)
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	_, err := g.Command(dir, "add", ".")
+	require.NoError(t, err)
+	_, err = g.Command(dir, "commit", "-m", "release "+version)
+	require.NoError(t, err)
+}
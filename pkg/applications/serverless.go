@@ -0,0 +1,196 @@
+package applications
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+const (
+	// KindKnativeService the kind of a Knative Serving Service workload
+	KindKnativeService = "KnativeService"
+
+	// KindInferenceService the kind of a KServe InferenceService workload
+	KindInferenceService = "InferenceService"
+)
+
+var (
+	// KnativeServiceResource the resource of Knative Serving Services
+	KnativeServiceResource = schema.GroupVersionResource{Group: "serving.knative.dev", Version: "v1", Resource: "services"}
+
+	// InferenceServiceResource the resource of KServe InferenceServices
+	InferenceServiceResource = schema.GroupVersionResource{Group: "serving.kserve.io", Version: "v1beta1", Resource: "inferenceservices"}
+
+	// serverlessResources the serverless resources discovered with the dynamic client
+	serverlessResources = []schema.GroupVersionResource{KnativeServiceResource, InferenceServiceResource}
+)
+
+// NewServerlessWorkload creates a workload from a Knative Service or KServe InferenceService
+func NewServerlessWorkload(u *unstructured.Unstructured) (*Workload, error) {
+	switch u.GetKind() {
+	case "Service":
+		return NewKnativeServiceWorkload(u)
+	case "InferenceService":
+		return NewInferenceServiceWorkload(u)
+	default:
+		return nil, fmt.Errorf("unsupported serverless kind %s", u.GetKind())
+	}
+}
+
+// NewKnativeServiceWorkload creates a workload from a Knative Service using its latest ready revision, traffic and URL
+func NewKnativeServiceWorkload(u *unstructured.Unstructured) (*Workload, error) {
+	meta, err := objectMeta(u)
+	if err != nil {
+		return nil, err
+	}
+	status, _, _ := unstructured.NestedMap(u.Object, "status")
+	templateLabels, _, _ := unstructured.NestedStringMap(u.Object, "spec", "template", "metadata", "labels")
+	w := newServerlessWorkload(KindKnativeService, meta, templateLabels, status, "latestReadyRevisionName")
+	if version, source := versionFromLabels(templateLabels); version != "" {
+		w.Version = version
+		w.VersionSource = source
+	}
+	w.Image = templateImage(u)
+	return w, nil
+}
+
+// NewInferenceServiceWorkload creates a workload from a KServe InferenceService using the latest ready revision and
+// traffic of its predictor
+func NewInferenceServiceWorkload(u *unstructured.Unstructured) (*Workload, error) {
+	meta, err := objectMeta(u)
+	if err != nil {
+		return nil, err
+	}
+	status, _, _ := unstructured.NestedMap(u.Object, "status")
+	predictor, _, _ := unstructured.NestedMap(u.Object, "status", "components", "predictor")
+	w := newServerlessWorkload(KindInferenceService, meta, meta.Labels, predictor, "latestReadyRevision")
+	w.URL, _, _ = unstructured.NestedString(status, "url")
+	return w, nil
+}
+
+// newServerlessWorkload creates a workload from the revision, traffic and URL of a knative style status
+func newServerlessWorkload(kind string, meta *metav1.ObjectMeta, appLabels map[string]string, status map[string]interface{}, revisionField string) *Workload {
+	w := &Workload{
+		Kind:       kind,
+		ObjectMeta: meta,
+		AppLabels:  appLabels,
+	}
+	w.URL, _, _ = unstructured.NestedString(status, "url")
+	w.Revision, _, _ = unstructured.NestedString(status, revisionField)
+	if w.Revision != "" {
+		w.Version = revisionVersion(w.Revision)
+		w.VersionSource = VersionSourceRevision
+	}
+	traffic, _, _ := unstructured.NestedSlice(status, "traffic")
+	for _, t := range traffic {
+		m, ok := t.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		target := TrafficTarget{}
+		target.RevisionName, _, _ = unstructured.NestedString(m, "revisionName")
+		target.Tag, _, _ = unstructured.NestedString(m, "tag")
+		target.LatestRevision, _, _ = unstructured.NestedBool(m, "latestRevision")
+		switch v := m["percent"].(type) {
+		case int64:
+			target.Percent = v
+		case float64:
+			target.Percent = int64(v)
+		}
+		w.Traffic = append(w.Traffic, target)
+	}
+	return w
+}
+
+// templateImage returns the image of the first container of the pod template of a custom resource
+func templateImage(u *unstructured.Unstructured) string {
+	containers, _, _ := unstructured.NestedSlice(u.Object, "spec", "template", "spec", "containers")
+	if len(containers) == 0 {
+		return ""
+	}
+	c, ok := containers[0].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	image, _, _ := unstructured.NestedString(c, "image")
+	return image
+}
+
+func objectMeta(u *unstructured.Unstructured) (*metav1.ObjectMeta, error) {
+	meta := &metav1.ObjectMeta{}
+	m, _, _ := unstructured.NestedMap(u.Object, "metadata")
+	err := runtime.DefaultUnstructuredConverter.FromUnstructured(m, meta)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert the metadata of %s %s: %w", u.GetKind(), u.GetName(), err)
+	}
+	return meta, nil
+}
+
+// isInferenceServiceComponent returns true if the Knative Service was created by KServe for an InferenceService
+// which is shown instead
+func isInferenceServiceComponent(meta *metav1.ObjectMeta) bool {
+	for i := range meta.OwnerReferences {
+		if meta.OwnerReferences[i].Kind == KindInferenceService {
+			return true
+		}
+	}
+	return false
+}
+
+// getServerlessWorkloads returns the Knative Services and KServe InferenceServices in the namespace. Resources which
+// are not installed or cannot be listed are skipped
+func getServerlessWorkloads(ctx context.Context, dynamicClient dynamic.Interface, ns, selector string) ([]*Workload, error) {
+	if dynamicClient == nil {
+		return nil, nil
+	}
+	var answer []*Workload
+	for _, resource := range serverlessResources {
+		items, err := listOptionalResources(ctx, dynamicClient, resource, ns, selector)
+		if err != nil {
+			return nil, err
+		}
+		workloads, err := toServerlessWorkloads(items)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create workloads for %s in namespace %s: %w", resource.GroupResource().String(), ns, err)
+		}
+		answer = append(answer, workloads...)
+	}
+	return answer, nil
+}
+
+// listOptionalResources lists the custom resources in the namespace returning nothing if the resource is not
+// installed or cannot be listed
+func listOptionalResources(ctx context.Context, dynamicClient dynamic.Interface, resource schema.GroupVersionResource, ns, selector string) ([]unstructured.Unstructured, error) {
+	if dynamicClient == nil {
+		return nil, nil
+	}
+	list, err := dynamicClient.Resource(resource).Namespace(ns).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		if skipWorkloadError(resource.GroupResource().String(), ns, err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+// toServerlessWorkloads converts the serverless resources into workloads
+func toServerlessWorkloads(items []unstructured.Unstructured) ([]*Workload, error) {
+	var answer []*Workload
+	for i := range items {
+		w, err := NewServerlessWorkload(&items[i])
+		if err != nil {
+			return nil, err
+		}
+		if w.Kind == KindKnativeService && isInferenceServiceComponent(w.ObjectMeta) {
+			continue
+		}
+		answer = append(answer, w)
+	}
+	return answer, nil
+}
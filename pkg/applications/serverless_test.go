@@ -0,0 +1,111 @@
+package applications
+
+import (
+	"context"
+	"testing"
+
+	v1 "github.com/jenkins-x/jx-api/v4/pkg/apis/jenkins.io/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakedyn "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestGetDeploymentsServerless(t *testing.T) {
+	ns := "jx-staging"
+	knativeService := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "serving.knative.dev/v1",
+		"kind":       "Service",
+		"metadata": map[string]interface{}{
+			"name":      "myfunc",
+			"namespace": ns,
+		},
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"labels": map[string]interface{}{"version": "1.2.0"},
+				},
+			},
+		},
+		"status": map[string]interface{}{
+			"url":                     "https://myfunc.jx-staging.example.com",
+			"latestReadyRevisionName": "myfunc-00002",
+			"traffic": []interface{}{
+				map[string]interface{}{"revisionName": "myfunc-00002", "percent": int64(80), "latestRevision": true},
+				map[string]interface{}{"revisionName": "myfunc-00001", "percent": int64(20), "tag": "previous"},
+			},
+		},
+	}}
+	inferenceService := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "serving.kserve.io/v1beta1",
+		"kind":       "InferenceService",
+		"metadata": map[string]interface{}{
+			"name":      "mymodel",
+			"namespace": ns,
+		},
+		"status": map[string]interface{}{
+			"url": "https://mymodel.jx-staging.example.com",
+			"components": map[string]interface{}{
+				"predictor": map[string]interface{}{
+					"latestReadyRevision": "mymodel-predictor-00003",
+					"traffic": []interface{}{
+						map[string]interface{}{"revisionName": "mymodel-predictor-00003", "percent": int64(100), "latestRevision": true},
+					},
+				},
+			},
+		},
+	}}
+	predictorService := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "serving.knative.dev/v1",
+		"kind":       "Service",
+		"metadata": map[string]interface{}{
+			"name":      "mymodel-predictor",
+			"namespace": ns,
+			"ownerReferences": []interface{}{
+				map[string]interface{}{"apiVersion": "serving.kserve.io/v1beta1", "kind": "InferenceService", "name": "mymodel", "uid": "1234"},
+			},
+		},
+	}}
+	dynamicClient := fakedyn.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		KnativeServiceResource:   "ServiceList",
+		InferenceServiceResource: "InferenceServiceList",
+	}, knativeService, inferenceService, predictorService)
+	env := &v1.Environment{
+		ObjectMeta: metav1.ObjectMeta{Name: "staging"},
+		Spec:       v1.EnvironmentSpec{Namespace: ns},
+	}
+
+	deployments, err := getDeployments(context.TODO(), fake.NewSimpleClientset(), dynamicClient, nil, ns, env, "")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Deployment{
+		"KnativeService/myfunc": {
+			Name:          "myfunc",
+			Kind:          KindKnativeService,
+			Workload:      "myfunc",
+			Version:       "1.2.0",
+			VersionSource: LabelVersionSource("version"),
+			URL:           "https://myfunc.jx-staging.example.com",
+			Revision:      "myfunc-00002",
+			Traffic: []TrafficTarget{
+				{RevisionName: "myfunc-00002", Percent: 80, LatestRevision: true},
+				{RevisionName: "myfunc-00001", Percent: 20, Tag: "previous"},
+			},
+		},
+		"InferenceService/mymodel": {
+			Name:          "mymodel",
+			Kind:          KindInferenceService,
+			Workload:      "mymodel",
+			Version:       "00003",
+			VersionSource: VersionSourceRevision,
+			URL:           "https://mymodel.jx-staging.example.com",
+			Revision:      "mymodel-predictor-00003",
+			Traffic: []TrafficTarget{
+				{RevisionName: "mymodel-predictor-00003", Percent: 100, LatestRevision: true},
+			},
+		},
+	}, deployments)
+}
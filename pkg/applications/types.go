@@ -4,33 +4,98 @@ import (
 	v1 "github.com/jenkins-x/jx-api/v4/pkg/apis/jenkins.io/v1"
 )
 
-// RevisionLabel the label used to show the revision
-const RevisionLabel = "serving.knative.dev/revision"
+const (
+	// RevisionLabel the label used to show the revision
+	RevisionLabel = "serving.knative.dev/revision"
+
+	// ApplicationsAnnotation the annotation on a SourceRepository listing the comma separated names of the
+	// applications it produces if it is a monorepo
+	ApplicationsAnnotation = "jenkins.io/applications"
+
+	// EnvironmentStatusError the status of an environment which could not be fetched
+	EnvironmentStatusError = "Error"
+
+	// EnvironmentStatusForbidden the status of an environment which the user is not allowed to fetch
+	EnvironmentStatusForbidden = "Forbidden"
+)
 
 // Deployment represents an application deployment in a single environment
 type Deployment struct {
-	Name    string `json:"name,omitempty"`
-	Pods    string `json:"pods,omitempty"`
-	Version string `json:"version,omitempty"`
-	URL     string `json:"url,omitempty"`
-	Canary  bool   `json:"canary,omitempty"`
+	Name string `json:"name,omitempty"`
+	Kind string `json:"kind,omitempty"`
+	// Workload the name of the kubernetes resource of the workload
+	Workload string `json:"workload,omitempty"`
+	Pods     string `json:"pods,omitempty"`
+	Version  string `json:"version,omitempty"`
+	URL      string `json:"url,omitempty"`
+	Canary   bool   `json:"canary,omitempty"`
+	// VersionSource where the version was found such as label:version, helm-release or image
+	VersionSource string `json:"versionSource,omitempty"`
+	// Image the image of the primary container of the workload
+	Image string `json:"image,omitempty"`
+	// Revision the latest ready revision of a serverless workload
+	Revision string `json:"revision,omitempty"`
+	// Traffic how the traffic of a serverless workload is split between its revisions
+	Traffic []TrafficTarget `json:"traffic,omitempty"`
+	// Rollout the progressive delivery status if the workload is managed by a progressive delivery backend
+	Rollout *RolloutStatus `json:"rollout,omitempty"`
+	// Helm the latest revision of the helm release which deployed the workload
+	Helm *HelmRelease `json:"helm,omitempty"`
+	// Metadata the labels and annotations of the workload used to match it to an application
+	Metadata map[string]string `json:"-"`
 	// *appsv1.Deployment `json:"deployment,omitempty"`
 }
 
+// TrafficTarget the share of the traffic of a serverless workload routed to a revision
+type TrafficTarget struct {
+	RevisionName   string `json:"revisionName,omitempty"`
+	Percent        int64  `json:"percent"`
+	Tag            string `json:"tag,omitempty"`
+	LatestRevision bool   `json:"latestRevision,omitempty"`
+}
+
+// RolloutStatus the status of a workload managed by a progressive delivery backend such as Flagger or Argo Rollouts
+type RolloutStatus struct {
+	// Backend the name of the progressive delivery backend
+	Backend string `json:"backend"`
+	// Phase the phase of the rollout such as Progressing or Healthy
+	Phase string `json:"phase,omitempty"`
+	// StableVersion the version receiving the stable traffic
+	StableVersion string `json:"stableVersion,omitempty"`
+	// CanaryVersion the version being rolled out if a rollout is in progress
+	CanaryVersion string `json:"canaryVersion,omitempty"`
+	// Step the current step out of the total steps of the rollout such as 2/5
+	Step string `json:"step,omitempty"`
+	// Weight the percentage of traffic sent to the canary version
+	Weight int64 `json:"weight,omitempty"`
+	// FailedChecks the number of failed checks of the canary analysis
+	FailedChecks int64 `json:"failedChecks,omitempty"`
+	// LastTransitionTime when the rollout last changed phase
+	LastTransitionTime string `json:"lastTransitionTime,omitempty"`
+}
+
 // Environment represents an environment in which an application has been
 // deployed
 type Environment struct {
 	v1.Environment `json:"environment,omitempty"`
 	Deployments    []Deployment `json:"deployments,omitempty"`
+	// FetchStatus is empty if the environment was fetched otherwise the reason it could not be
+	FetchStatus string `json:"fetchStatus,omitempty"`
+	// FetchError the error fetching the environment if it could not be fetched
+	FetchError string `json:"fetchError,omitempty"`
 }
 
 // Application represents an application in jx
 type Application struct {
 	*v1.SourceRepository `json:"sourceRepository"`
 	Environments         map[string]Environment `json:"environments"`
+	// AppName the name of the application if the repository produces several applications
+	AppName string `json:"name,omitempty"`
 }
 
 // List is a collection of applications
 type List struct {
 	Items []Application `json:"applications,omitempty"`
+	// Failures the environments which could not be fetched with their FetchStatus and FetchError
+	Failures []Environment `json:"failures,omitempty"`
 }
@@ -0,0 +1,165 @@
+package applications
+
+import (
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// CompareVersions compares two application versions returning -1, 0 or 1 if a is older than, equal to or newer than b.
+// Versions are compared using semantic versioning when both parse, otherwise falling back to a string comparison.
+// An empty version is considered older than any other version
+func CompareVersions(a, b string) int {
+	if a == b {
+		return 0
+	}
+	if a == "" {
+		return -1
+	}
+	if b == "" {
+		return 1
+	}
+	va, errA := semver.NewVersion(a)
+	vb, errB := semver.NewVersion(b)
+	if errA == nil && errB == nil {
+		return va.Compare(vb)
+	}
+	return strings.Compare(a, b)
+}
+
+const (
+	// AppVersionLabel the recommended kubernetes label for the version of an application
+	AppVersionLabel = "app.kubernetes.io/version"
+
+	// HelmChartLabel the label helm charts add with the chart name and version
+	HelmChartLabel = "helm.sh/chart"
+
+	// VersionSourceRevision the version is the generation of the latest ready revision of a serverless workload
+	VersionSourceRevision = "revision"
+
+	// VersionSourceHelmRelease the version is the app version of the deployed helm release
+	VersionSourceHelmRelease = "helm-release"
+
+	// VersionSourceImage the version is the tag or digest of the image of the primary container
+	VersionSourceImage = "image"
+
+	// versionSourceLabelPrefix the prefix of the source of a version found from a label
+	versionSourceLabelPrefix = "label:"
+)
+
+// versionLabels the labels containing the version in order of preference along with whether they contain the chart
+// name before the version
+var versionLabels = []struct {
+	label string
+	chart bool
+}{
+	{label: "version"},
+	{label: AppVersionLabel},
+	{label: "chart", chart: true},
+	{label: HelmChartLabel, chart: true},
+}
+
+// LabelVersionSource returns the version source of a version found in the given label
+func LabelVersionSource(label string) string {
+	return versionSourceLabelPrefix + label
+}
+
+// versionFromLabels returns the version from the labels along with its source or blank if it cannot be deduced
+func versionFromLabels(labels map[string]string) (string, string) {
+	for _, l := range versionLabels {
+		v := labels[l.label]
+		if v == "" {
+			continue
+		}
+		if l.chart {
+			v = chartVersion(v)
+		}
+		return v, LabelVersionSource(l.label)
+	}
+
+	// find the kserve revision
+	revision := labels[RevisionLabel]
+	if revision != "" {
+		return revisionVersion(revision), LabelVersionSource(RevisionLabel)
+	}
+	return "", ""
+}
+
+// chartVersion returns the version from a chart label such as 1.2.3-rc1 for my-app-1.2.3-rc1. The version is the
+// longest suffix which is a semantic version so that chart names containing digits such as app-2fa-1.0.0 are handled,
+// falling back to the last segment
+func chartVersion(chart string) string {
+	answer := ""
+	for i := len(chart) - 1; i > 0; i-- {
+		if chart[i-1] != '-' {
+			continue
+		}
+		if _, err := semver.StrictNewVersion(chart[i:]); err == nil {
+			answer = chart[i:]
+		}
+	}
+	if answer != "" {
+		return answer
+	}
+	arr := strings.Split(chart, "-")
+	last := arr[len(arr)-1]
+	if last != "" {
+		return last
+	}
+	return chart
+}
+
+// ImageVersion returns the tag of the image or its digest if it has no tag
+func ImageVersion(image string) string {
+	name, digest, _ := strings.Cut(image, "@")
+	idx := strings.LastIndex(name, ":")
+	if idx > strings.LastIndex(name, "/") {
+		return name[idx+1:]
+	}
+	return digest
+}
+
+// primaryImage returns the image of the container named after the workload or the first container
+func primaryImage(name string, spec *corev1.PodSpec) string {
+	for i := range spec.Containers {
+		if spec.Containers[i].Name == name {
+			return spec.Containers[i].Image
+		}
+	}
+	if len(spec.Containers) > 0 {
+		return spec.Containers[0].Image
+	}
+	return ""
+}
+
+// version returns the version of the workload along with its source. The labels of the resource are preferred
+// followed by the version of its template or revision, its helm release and finally the image it runs
+func (w *Workload) version() (string, string) {
+	if v, source := versionFromLabels(w.ObjectMeta.Labels); v != "" {
+		return v, source
+	}
+	if w.Version != "" {
+		return w.Version, w.VersionSource
+	}
+	if w.DeployedHelmRelease != nil && w.DeployedHelmRelease.AppVersion != "" {
+		return w.DeployedHelmRelease.AppVersion, VersionSourceHelmRelease
+	}
+	if v := ImageVersion(w.Image); v != "" {
+		return v, VersionSourceImage
+	}
+	return "", ""
+}
+
+// ImageVersionMismatch returns true if the version of the deployment was found from its metadata but differs from
+// the version of the image it is running
+func (d *Deployment) ImageVersionMismatch() bool {
+	if d.Version == "" || d.VersionSource == VersionSourceImage {
+		return false
+	}
+	imageVersion := ImageVersion(d.Image)
+	if imageVersion == "" || strings.HasPrefix(imageVersion, "sha256:") {
+		return false
+	}
+	return strings.TrimPrefix(imageVersion, "v") != strings.TrimPrefix(d.Version, "v")
+}
@@ -0,0 +1,656 @@
+package applications
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	v1 "github.com/jenkins-x/jx-api/v4/pkg/apis/jenkins.io/v1"
+	jxc "github.com/jenkins-x/jx-api/v4/pkg/client/clientset/versioned"
+	jxinformers "github.com/jenkins-x/jx-api/v4/pkg/client/informers/externalversions"
+	jxlisters "github.com/jenkins-x/jx-api/v4/pkg/client/listers/jenkins.io/v1"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	batchlisters "k8s.io/client-go/listers/batch/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Watcher keeps the applications up to date using informers on the SourceRepositories, Environments and the
+// Deployments in each environment namespace. Remote environments are polled on a separate interval as they
+// require fetching the environment git repository.
+//
+// The selectors, environments, SkipRemote, RemoteTimeout and Strict options are used as they are when fetching the
+// applications once. The Timeout and Parallelism options are ignored as the informers run until the watcher is stopped
+type Watcher struct {
+	GetOptions
+
+	// RemotePollInterval how often the remote environments are polled. If zero they are only fetched when started
+	RemotePollInterval time.Duration
+
+	changes           chan struct{}
+	ctx               context.Context
+	stopCh            <-chan struct{}
+	lock              sync.Mutex
+	srLister          jxlisters.SourceRepositoryLister
+	envLister         jxlisters.EnvironmentLister
+	workloadListers   map[string]*workloadListers
+	remoteDeployments map[string]map[string]Deployment
+	remoteErrors      map[string]error
+	urls              map[string]string
+}
+
+// NewWatcher creates a new watcher of the applications in the given dev namespace
+func NewWatcher(jxClient jxc.Interface, kubeClient kubernetes.Interface, namespace string, g gitclient.Interface, remotePollInterval time.Duration) *Watcher {
+	return NewWatcherWithOptions(&GetOptions{
+		JXClient:   jxClient,
+		KubeClient: kubeClient,
+		Namespace:  namespace,
+		GitClient:  g,
+	}, remotePollInterval)
+}
+
+// NewWatcherWithOptions creates a new watcher of the applications using the given options
+func NewWatcherWithOptions(o *GetOptions, remotePollInterval time.Duration) *Watcher {
+	return &Watcher{
+		GetOptions:         *o,
+		RemotePollInterval: remotePollInterval,
+		changes:            make(chan struct{}, 1),
+		workloadListers:    map[string]*workloadListers{},
+		remoteDeployments:  map[string]map[string]Deployment{},
+		urls:               map[string]string{},
+	}
+}
+
+// Changes returns the channel notified whenever the applications may have changed
+func (w *Watcher) Changes() <-chan struct{} {
+	return w.changes
+}
+
+// Start starts the informers and the polling of remote environments until the context is done. The context is also
+// used for the API calls and remote environment fetches made while watching so that they are cancelled with it
+func (w *Watcher) Start(ctx context.Context) error {
+	stopCh := ctx.Done()
+	w.ctx = ctx
+	w.stopCh = stopCh
+
+	factory := jxinformers.NewSharedInformerFactoryWithOptions(w.JXClient, 0, jxinformers.WithNamespace(w.Namespace))
+	srFactory := factory
+	if w.RepositorySelector != "" {
+		srFactory = jxinformers.NewSharedInformerFactoryWithOptions(w.JXClient, 0, jxinformers.WithNamespace(w.Namespace),
+			jxinformers.WithTweakListOptions(func(o *metav1.ListOptions) {
+				o.LabelSelector = w.RepositorySelector
+			}))
+	}
+	srInformer := srFactory.Jenkins().V1().SourceRepositories()
+	envInformer := factory.Jenkins().V1().Environments()
+	_, err := srInformer.Informer().AddEventHandler(w.eventHandler())
+	if err != nil {
+		return fmt.Errorf("failed to watch SourceRepositories in namespace %s: %w", w.Namespace, err)
+	}
+	_, err = envInformer.Informer().AddEventHandler(w.eventHandler())
+	if err != nil {
+		return fmt.Errorf("failed to watch Environments in namespace %s: %w", w.Namespace, err)
+	}
+	w.srLister = srInformer.Lister()
+	w.envLister = envInformer.Lister()
+
+	for _, f := range []jxinformers.SharedInformerFactory{factory, srFactory} {
+		f.Start(stopCh)
+		for t, synced := range f.WaitForCacheSync(stopCh) {
+			if !synced {
+				return fmt.Errorf("failed to sync the informer for %v in namespace %s", t, w.Namespace)
+			}
+		}
+	}
+
+	if w.SkipRemote {
+		return nil
+	}
+	w.pollRemoteEnvironments()
+	if w.RemotePollInterval > 0 {
+		go func() {
+			ticker := time.NewTicker(w.RemotePollInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-stopCh:
+					return
+				case <-ticker.C:
+					w.pollRemoteEnvironments()
+				}
+			}
+		}()
+	}
+	return nil
+}
+
+// List returns the current applications from the informer caches. If Strict is enabled an error combining the
+// failures of every environment is returned if any environment could not be fetched
+func (w *Watcher) List() (List, error) {
+	envs, err := w.envLister.Environments(w.Namespace).List(labels.Everything())
+	if err != nil {
+		return List{}, fmt.Errorf("failed to list Environments in namespace %s: %w", w.Namespace, err)
+	}
+	srs, err := w.srLister.SourceRepositories(w.Namespace).List(labels.Everything())
+	if err != nil {
+		return List{}, fmt.Errorf("failed to list SourceRepositories in namespace %s: %w", w.Namespace, err)
+	}
+	repositories := make([]v1.SourceRepository, 0, len(srs))
+	for _, sr := range srs {
+		repositories = append(repositories, *sr.DeepCopy())
+	}
+	sort.Slice(repositories, func(i, j int) bool {
+		return repositories[i].Name < repositories[j].Name
+	})
+
+	envMap := map[string]*v1.Environment{}
+	for _, env := range envs {
+		envMap[env.Name] = env.DeepCopy()
+	}
+	permanentEnvsMap := PermanentEnvironments(envMap)
+
+	var permanentEnvs []*v1.Environment
+	for _, env := range permanentEnvsMap {
+		if env.Spec.Kind == v1.EnvironmentKindTypeDevelopment || !w.includesEnvironment(env) {
+			continue
+		}
+		if env.Spec.RemoteCluster && w.SkipRemote {
+			continue
+		}
+		permanentEnvs = append(permanentEnvs, env)
+	}
+	sortEnvironments(permanentEnvs)
+
+	deployments := make(map[string]map[string]Deployment)
+	failures := map[string]error{}
+	var joined []error
+	for _, env := range permanentEnvs {
+		ns := env.Spec.Namespace
+		if env.Spec.RemoteCluster {
+			w.lock.Lock()
+			deployments[ns] = w.remoteDeployments[ns]
+			err = w.remoteErrors[ns]
+			w.lock.Unlock()
+		} else {
+			deployments[ns], err = w.getDeployments(ns, env)
+		}
+		if err != nil {
+			delete(deployments, ns)
+			failures[ns] = err
+			joined = append(joined, fmt.Errorf("environment %s: %w", env.Name, err))
+		}
+	}
+	if len(joined) > 0 && w.Strict {
+		return List{}, errors.Join(joined...)
+	}
+	list := NewListWithMatchStrategy(repositories, permanentEnvsMap, deployments, w.matchStrategy())
+	list.setEnvironmentFailures(permanentEnvsMap, failures)
+	return list, nil
+}
+
+// workloadListers the listers of the workloads in a namespace. A lister is nil if its kind cannot be listed
+type workloadListers struct {
+	deployments  appslisters.DeploymentLister
+	statefulSets appslisters.StatefulSetLister
+	daemonSets   appslisters.DaemonSetLister
+	cronJobs     batchlisters.CronJobLister
+	helmReleases corelisters.SecretLister
+	replicaSets  appslisters.ReplicaSetLister
+	dynamic      map[schema.GroupVersionResource]cache.GenericLister
+
+	// watchURLs is true if the Services and Ingresses are watched so that the cached URLs are invalidated
+	watchURLs bool
+}
+
+// getDeployments returns the deployments of the workloads in the namespace from the informer caches, lazily
+// starting the informers the first time an environment namespace is seen
+func (w *Watcher) getDeployments(ns string, env *v1.Environment) (map[string]Deployment, error) {
+	listers, err := w.namespaceListers(ns)
+	if err != nil {
+		return nil, err
+	}
+	workloads, err := listers.list(ns)
+	if err != nil {
+		return nil, err
+	}
+	workloads, err = applyProgressiveDelivery(w.ctx, listers.listReplicaSets, w.progressiveDeliveryBackends(), workloads, func(resource schema.GroupVersionResource) ([]unstructured.Unstructured, error) {
+		return listers.listDynamic(ns, resource)
+	})
+	if err != nil {
+		return nil, err
+	}
+	releases, err := listers.listHelmReleases(ns)
+	if err != nil {
+		return nil, err
+	}
+	workloads = applyHelmReleases(workloads, releases)
+
+	answer := map[string]Deployment{}
+	for _, wl := range workloads {
+		deployment := CreateWorkloadDeployment(wl, env)
+		if wl.hasServiceURL() {
+			deployment.URL = w.workloadURL(ns, deployment.Name, listers.watchURLs)
+		}
+		answer[workloadKey(wl.Kind, wl.ObjectMeta.Name)] = deployment
+	}
+	return answer, nil
+}
+
+// list returns the workloads in the namespace from the informer caches
+func (l *workloadListers) list(ns string) ([]*Workload, error) {
+	var answer []*Workload
+	deps, err := l.deployments.Deployments(ns).List(labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Deployments in namespace %s: %w", ns, err)
+	}
+	for _, d := range deps {
+		wl, err := NewDeploymentWorkload(d)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create workload for Deployment %s in namespace %s: %w", d.Name, ns, err)
+		}
+		answer = append(answer, wl)
+	}
+	if l.statefulSets != nil {
+		statefulSets, err := l.statefulSets.StatefulSets(ns).List(labels.Everything())
+		if err != nil {
+			return nil, fmt.Errorf("failed to list StatefulSets in namespace %s: %w", ns, err)
+		}
+		for _, ss := range statefulSets {
+			wl, err := NewStatefulSetWorkload(ss)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create workload for StatefulSet %s in namespace %s: %w", ss.Name, ns, err)
+			}
+			answer = append(answer, wl)
+		}
+	}
+	if l.daemonSets != nil {
+		daemonSets, err := l.daemonSets.DaemonSets(ns).List(labels.Everything())
+		if err != nil {
+			return nil, fmt.Errorf("failed to list DaemonSets in namespace %s: %w", ns, err)
+		}
+		for _, ds := range daemonSets {
+			wl, err := NewDaemonSetWorkload(ds)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create workload for DaemonSet %s in namespace %s: %w", ds.Name, ns, err)
+			}
+			answer = append(answer, wl)
+		}
+	}
+	if l.cronJobs != nil {
+		cronJobs, err := l.cronJobs.CronJobs(ns).List(labels.Everything())
+		if err != nil {
+			return nil, fmt.Errorf("failed to list CronJobs in namespace %s: %w", ns, err)
+		}
+		for _, cj := range cronJobs {
+			answer = append(answer, NewCronJobWorkload(cj))
+		}
+	}
+	for _, resource := range serverlessResources {
+		items, err := l.listDynamic(ns, resource)
+		if err != nil {
+			return nil, err
+		}
+		workloads, err := toServerlessWorkloads(items)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create serverless workloads in namespace %s: %w", ns, err)
+		}
+		answer = append(answer, workloads...)
+	}
+	return answer, nil
+}
+
+// listDynamic returns the custom resources in the namespace from the informer cache or nothing if the resource
+// cannot be listed
+func (l *workloadListers) listDynamic(ns string, resource schema.GroupVersionResource) ([]unstructured.Unstructured, error) {
+	lister := l.dynamic[resource]
+	if lister == nil {
+		return nil, nil
+	}
+	objects, err := lister.ByNamespace(ns).List(labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s in namespace %s: %w", resource.GroupResource().String(), ns, err)
+	}
+	answer := make([]unstructured.Unstructured, 0, len(objects))
+	for _, o := range objects {
+		u, ok := o.(*unstructured.Unstructured)
+		if ok {
+			answer = append(answer, *u)
+		}
+	}
+	return answer, nil
+}
+
+func (w *Watcher) namespaceListers(ns string) (*workloadListers, error) {
+	w.lock.Lock()
+	listers := w.workloadListers[ns]
+	w.lock.Unlock()
+	if listers != nil {
+		return listers, nil
+	}
+
+	// lets check we can list the workloads first as an informer would retry forever
+	ctx := w.ctx
+	limit := metav1.ListOptions{Limit: 1}
+	_, err := w.KubeClient.AppsV1().Deployments(ns).List(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+	canList := func(kind string, err error) (bool, error) {
+		if err == nil {
+			return true, nil
+		}
+		if skipWorkloadError(kind, ns, err) {
+			return false, nil
+		}
+		return false, err
+	}
+	_, err = w.KubeClient.AppsV1().StatefulSets(ns).List(ctx, limit)
+	statefulSets, err := canList(KindStatefulSet, err)
+	if err != nil {
+		return nil, err
+	}
+	_, err = w.KubeClient.AppsV1().DaemonSets(ns).List(ctx, limit)
+	daemonSets, err := canList(KindDaemonSet, err)
+	if err != nil {
+		return nil, err
+	}
+	_, err = w.KubeClient.BatchV1().CronJobs(ns).List(ctx, limit)
+	cronJobs, err := canList(KindCronJob, err)
+	if err != nil {
+		return nil, err
+	}
+
+	factory := informers.NewSharedInformerFactoryWithOptions(w.KubeClient, 0, informers.WithNamespace(ns),
+		informers.WithTweakListOptions(w.tweakDeploymentListOptions))
+	listers = &workloadListers{dynamic: map[schema.GroupVersionResource]cache.GenericLister{}}
+	var sharedInformers []cache.SharedIndexInformer
+	deploymentInformer := factory.Apps().V1().Deployments()
+	listers.deployments = deploymentInformer.Lister()
+	sharedInformers = append(sharedInformers, deploymentInformer.Informer())
+	if statefulSets {
+		informer := factory.Apps().V1().StatefulSets()
+		listers.statefulSets = informer.Lister()
+		sharedInformers = append(sharedInformers, informer.Informer())
+	}
+	if daemonSets {
+		informer := factory.Apps().V1().DaemonSets()
+		listers.daemonSets = informer.Lister()
+		sharedInformers = append(sharedInformers, informer.Informer())
+	}
+	if cronJobs {
+		informer := factory.Batch().V1().CronJobs()
+		listers.cronJobs = informer.Lister()
+		sharedInformers = append(sharedInformers, informer.Informer())
+	}
+	for _, informer := range sharedInformers {
+		_, err = informer.AddEventHandler(w.eventHandler())
+		if err != nil {
+			return nil, fmt.Errorf("failed to watch workloads in namespace %s: %w", ns, err)
+		}
+	}
+	factory.Start(w.stopCh)
+	for t, synced := range factory.WaitForCacheSync(w.stopCh) {
+		if !synced {
+			return nil, fmt.Errorf("failed to sync the informer for %v in namespace %s", t, ns)
+		}
+	}
+
+	// the helm release secrets are watched separately so that only they are cached rather than every secret
+	helmSelector := metav1.ListOptions{LabelSelector: HelmReleaseSelector, Limit: 1}
+	_, err = w.KubeClient.CoreV1().Secrets(ns).List(ctx, helmSelector)
+	helmReleases, err := canList("Secret", err)
+	if err != nil {
+		return nil, err
+	}
+	if helmReleases {
+		helmFactory := informers.NewSharedInformerFactoryWithOptions(w.KubeClient, 0, informers.WithNamespace(ns),
+			informers.WithTweakListOptions(func(o *metav1.ListOptions) {
+				o.LabelSelector = HelmReleaseSelector
+			}))
+		informer := helmFactory.Core().V1().Secrets()
+		listers.helmReleases = informer.Lister()
+		_, err = informer.Informer().AddEventHandler(w.eventHandler())
+		if err != nil {
+			return nil, fmt.Errorf("failed to watch helm releases in namespace %s: %w", ns, err)
+		}
+		helmFactory.Start(w.stopCh)
+		for t, synced := range helmFactory.WaitForCacheSync(w.stopCh) {
+			if !synced {
+				return nil, fmt.Errorf("failed to sync the informer for %v in namespace %s", t, ns)
+			}
+		}
+	}
+
+	// the services and ingresses are watched without the DeploymentSelector as they may not share its labels
+	_, err = w.KubeClient.CoreV1().Services(ns).List(ctx, limit)
+	watchServices, err := canList("Service", err)
+	if err != nil {
+		return nil, err
+	}
+	_, err = w.KubeClient.NetworkingV1().Ingresses(ns).List(ctx, limit)
+	watchIngresses, err := canList("Ingress", err)
+	if err != nil {
+		return nil, err
+	}
+	if watchServices && watchIngresses {
+		urlFactory := informers.NewSharedInformerFactoryWithOptions(w.KubeClient, 0, informers.WithNamespace(ns))
+		for _, informer := range []cache.SharedIndexInformer{urlFactory.Core().V1().Services().Informer(), urlFactory.Networking().V1().Ingresses().Informer()} {
+			_, err = informer.AddEventHandler(w.urlEventHandler())
+			if err != nil {
+				return nil, fmt.Errorf("failed to watch services in namespace %s: %w", ns, err)
+			}
+		}
+		urlFactory.Start(w.stopCh)
+		for t, synced := range urlFactory.WaitForCacheSync(w.stopCh) {
+			if !synced {
+				return nil, fmt.Errorf("failed to sync the informer for %v in namespace %s", t, ns)
+			}
+		}
+		listers.watchURLs = true
+	}
+
+	if w.DynamicClient != nil {
+		dynamicFactory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(w.DynamicClient, 0, ns, w.tweakDeploymentListOptions)
+		resources := append(append([]schema.GroupVersionResource{}, serverlessResources...), progressiveDeliveryResources(w.progressiveDeliveryBackends())...)
+		for _, resource := range resources {
+			_, err = w.DynamicClient.Resource(resource).Namespace(ns).List(ctx, limit)
+			ok, err := canList(resource.GroupResource().String(), err)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+			informer := dynamicFactory.ForResource(resource)
+			_, err = informer.Informer().AddEventHandler(w.eventHandler())
+			if err != nil {
+				return nil, fmt.Errorf("failed to watch %s in namespace %s: %w", resource.GroupResource().String(), ns, err)
+			}
+			listers.dynamic[resource] = informer.Lister()
+		}
+		dynamicFactory.Start(w.stopCh)
+		for r, synced := range dynamicFactory.WaitForCacheSync(w.stopCh) {
+			if !synced {
+				return nil, fmt.Errorf("failed to sync the informer for %s in namespace %s", r.String(), ns)
+			}
+		}
+	}
+
+	// the ReplicaSets of Rollouts are watched so that their stable version is found without an API call per render
+	if listers.dynamic[RolloutResource] != nil {
+		rolloutSelector := metav1.ListOptions{LabelSelector: rolloutsPodTemplateHashLabel, Limit: 1}
+		_, err = w.KubeClient.AppsV1().ReplicaSets(ns).List(ctx, rolloutSelector)
+		replicaSets, err := canList("ReplicaSet", err)
+		if err != nil {
+			return nil, err
+		}
+		if replicaSets {
+			rsFactory := informers.NewSharedInformerFactoryWithOptions(w.KubeClient, 0, informers.WithNamespace(ns),
+				informers.WithTweakListOptions(func(o *metav1.ListOptions) {
+					o.LabelSelector = rolloutsPodTemplateHashLabel
+				}))
+			informer := rsFactory.Apps().V1().ReplicaSets()
+			listers.replicaSets = informer.Lister()
+			rsFactory.Start(w.stopCh)
+			for t, synced := range rsFactory.WaitForCacheSync(w.stopCh) {
+				if !synced {
+					return nil, fmt.Errorf("failed to sync the informer for %v in namespace %s", t, ns)
+				}
+			}
+		}
+	}
+
+	w.lock.Lock()
+	w.workloadListers[ns] = listers
+	w.lock.Unlock()
+	return listers, nil
+}
+
+// listReplicaSets returns the ReplicaSets of Rollouts in the namespace from the informer cache
+func (l *workloadListers) listReplicaSets(_ context.Context, ns string, selector labels.Selector) ([]*appsv1.ReplicaSet, error) {
+	if l.replicaSets == nil {
+		return nil, nil
+	}
+	return l.replicaSets.ReplicaSets(ns).List(selector)
+}
+
+// listHelmReleases returns the helm releases in the namespace from the informer cache
+func (l *workloadListers) listHelmReleases(ns string) (HelmReleases, error) {
+	if l.helmReleases == nil {
+		return nil, nil
+	}
+	secrets, err := l.helmReleases.Secrets(ns).List(labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list helm releases in namespace %s: %w", ns, err)
+	}
+	return NewHelmReleases(secrets), nil
+}
+
+// tweakDeploymentListOptions filters the workloads in each environment by the DeploymentSelector
+func (w *Watcher) tweakDeploymentListOptions(o *metav1.ListOptions) {
+	o.LabelSelector = w.DeploymentSelector
+}
+
+// workloadURL returns the URL of the application in the namespace. The URL is only cached if the Services and
+// Ingresses of the namespace are watched so that the entry is invalidated whenever they change
+func (w *Watcher) workloadURL(ns, appName string, cached bool) string {
+	if !cached {
+		return WorkloadURL(w.ctx, w.KubeClient, ns, appName)
+	}
+	key := ns + "/" + appName
+	w.lock.Lock()
+	url, ok := w.urls[key]
+	w.lock.Unlock()
+	if ok {
+		return url
+	}
+	url = WorkloadURL(w.ctx, w.KubeClient, ns, appName)
+
+	w.lock.Lock()
+	w.urls[key] = url
+	w.lock.Unlock()
+	return url
+}
+
+// pollRemoteEnvironments fetches the deployments of every remote environment
+func (w *Watcher) pollRemoteEnvironments() {
+	envs, err := w.envLister.Environments(w.Namespace).List(labels.Everything())
+	if err != nil {
+		log.Logger().Warnf("failed to list Environments in namespace %s: %s", w.Namespace, err.Error())
+		return
+	}
+	remoteDeployments := map[string]map[string]Deployment{}
+	remoteErrors := map[string]error{}
+	for _, env := range envs {
+		if w.ctx.Err() != nil {
+			// lets keep the previous results rather than reporting every environment as cancelled
+			return
+		}
+		if !env.Spec.Kind.IsPermanent() || !env.Spec.RemoteCluster || !w.includesEnvironment(env) {
+			continue
+		}
+		envDeployments, err := w.getRemoteDeployments(w.ctx, env)
+		if err != nil {
+			remoteErrors[env.Spec.Namespace] = err
+			continue
+		}
+		remoteDeployments[env.Spec.Namespace] = envDeployments
+	}
+
+	w.lock.Lock()
+	w.remoteDeployments = remoteDeployments
+	w.remoteErrors = remoteErrors
+	w.lock.Unlock()
+	w.notify()
+}
+
+func (w *Watcher) eventHandler() cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(interface{}) {
+			w.notify()
+		},
+		UpdateFunc: func(interface{}, interface{}) {
+			w.notify()
+		},
+		DeleteFunc: func(interface{}) {
+			w.notify()
+		},
+	}
+}
+
+// urlEventHandler invalidates the cached URLs of the namespace of a changed Service or Ingress, as an Ingress or
+// Service may expose an application whatever its name, notifying a change if any URLs had been cached
+func (w *Watcher) urlEventHandler() cache.ResourceEventHandler {
+	invalidate := func(obj interface{}) {
+		key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+		if err != nil {
+			return
+		}
+		ns, _, err := cache.SplitMetaNamespaceKey(key)
+		if err != nil {
+			return
+		}
+		prefix := ns + "/"
+		found := false
+		w.lock.Lock()
+		for k := range w.urls {
+			if strings.HasPrefix(k, prefix) {
+				delete(w.urls, k)
+				found = true
+			}
+		}
+		w.lock.Unlock()
+		if found {
+			w.notify()
+		}
+	}
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: invalidate,
+		UpdateFunc: func(_, obj interface{}) {
+			invalidate(obj)
+		},
+		DeleteFunc: invalidate,
+	}
+}
+
+// notify signals a change without blocking if a change is already pending
+func (w *Watcher) notify() {
+	select {
+	case w.changes <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,283 @@
+package applications
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "github.com/jenkins-x/jx-api/v4/pkg/apis/jenkins.io/v1"
+	fakejx "github.com/jenkins-x/jx-api/v4/pkg/client/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestWatcher(t *testing.T) {
+	ns := "jx"
+	jxClient := fakejx.NewSimpleClientset(
+		&v1.Environment{
+			ObjectMeta: metav1.ObjectMeta{Name: "staging", Namespace: ns},
+			Spec: v1.EnvironmentSpec{
+				Namespace: "jx-staging",
+				Kind:      v1.EnvironmentKindTypePermanent,
+			},
+		},
+		&v1.SourceRepository{
+			ObjectMeta: metav1.ObjectMeta{Name: "myorg-myapp", Namespace: ns},
+			Spec: v1.SourceRepositorySpec{
+				Org:  "myorg",
+				Repo: "myapp",
+			},
+		},
+	)
+	kubeClient := fake.NewSimpleClientset()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	w := NewWatcher(jxClient, kubeClient, ns, nil, 0)
+	require.NoError(t, w.Start(ctx))
+
+	list, err := w.List()
+	require.NoError(t, err)
+	require.Len(t, list.Items, 1)
+	assert.Empty(t, list.Items[0].Environments, "should not be deployed yet")
+
+	// drain the notifications from the initial sync
+	drainChanges(w)
+
+	_, err = kubeClient.AppsV1().Deployments("jx-staging").Create(context.TODO(), newTestDeployment("myapp", "jx-staging", "1.2.3"), metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	select {
+	case <-w.Changes():
+	case <-time.After(10 * time.Second):
+		require.Fail(t, "timed out waiting for a change notification")
+	}
+
+	require.Eventually(t, func() bool {
+		list, err = w.List()
+		require.NoError(t, err)
+		return len(list.Items[0].Environments["staging"].Deployments) == 1
+	}, 10*time.Second, 50*time.Millisecond)
+	assert.Equal(t, "1.2.3", list.Items[0].Environments["staging"].Deployments[0].Version)
+}
+
+func TestWatcherWithOptions(t *testing.T) {
+	ns := "jx"
+	newSourceRepository := func(repo, team string) *v1.SourceRepository {
+		return &v1.SourceRepository{
+			ObjectMeta: metav1.ObjectMeta{Name: "myorg-" + repo, Namespace: ns, Labels: map[string]string{"team": team}},
+			Spec: v1.SourceRepositorySpec{
+				Org:  "myorg",
+				Repo: repo,
+			},
+		}
+	}
+	jxClient := fakejx.NewSimpleClientset(
+		&v1.Environment{
+			ObjectMeta: metav1.ObjectMeta{Name: "staging", Namespace: ns},
+			Spec: v1.EnvironmentSpec{
+				Namespace: "jx-staging",
+				Kind:      v1.EnvironmentKindTypePermanent,
+				Order:     100,
+			},
+		},
+		&v1.Environment{
+			ObjectMeta: metav1.ObjectMeta{Name: "production", Namespace: ns},
+			Spec: v1.EnvironmentSpec{
+				Namespace:     "jx-production",
+				Kind:          v1.EnvironmentKindTypePermanent,
+				Order:         200,
+				RemoteCluster: true,
+				Source:        v1.EnvironmentRepository{URL: "https://github.com/myorg/environment-production.git"},
+			},
+		},
+		newSourceRepository("myapp", "a"),
+		newSourceRepository("other", "a"),
+		newSourceRepository("lib", "b"),
+	)
+	myapp := newTestDeployment("myapp", "jx-staging", "1.2.3")
+	myapp.Labels["tier"] = "web"
+	kubeClient := fake.NewSimpleClientset(
+		myapp,
+		newTestDeployment("other", "jx-staging", "2.0.0"),
+		newTestDeployment("lib", "jx-staging", "3.0.0"),
+	)
+	newWatcher := func(t *testing.T, o GetOptions) *Watcher {
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		o.JXClient = jxClient
+		o.KubeClient = kubeClient
+		o.Namespace = ns
+		o.ReleaseFetcher = failingReleaseFetcher{}
+		o.RepositorySelector = "team=a"
+		o.DeploymentSelector = "tier=web"
+		w := NewWatcherWithOptions(&o, 0)
+		require.NoError(t, w.Start(ctx))
+		return w
+	}
+
+	t.Run("selectors", func(t *testing.T) {
+		w := newWatcher(t, GetOptions{SkipRemote: true})
+		list, err := w.List()
+		require.NoError(t, err)
+		require.Len(t, list.Items, 2, "should only include the SourceRepositories matching the selector")
+		assert.Equal(t, "myapp", list.Items[0].Name())
+		require.Len(t, list.Items[0].Environments["staging"].Deployments, 1)
+		assert.Equal(t, "1.2.3", list.Items[0].Environments["staging"].Deployments[0].Version)
+		assert.Equal(t, "other", list.Items[1].Name())
+		assert.Empty(t, list.Items[1].Environments, "should not include the Deployments which do not match the selector")
+		assert.Empty(t, list.Failures, "should not fetch the remote environment")
+	})
+
+	t.Run("remote failures", func(t *testing.T) {
+		w := newWatcher(t, GetOptions{})
+		list, err := w.List()
+		require.NoError(t, err)
+		require.Len(t, list.Failures, 1)
+		assert.Equal(t, "production", list.Failures[0].Name)
+		assert.Equal(t, EnvironmentStatusError, list.Items[0].Environments["production"].FetchStatus)
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		w := newWatcher(t, GetOptions{Strict: true})
+		_, err := w.List()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "environment production")
+	})
+}
+
+func TestWatcherURLs(t *testing.T) {
+	ns := "jx"
+	jxClient := fakejx.NewSimpleClientset(
+		&v1.Environment{
+			ObjectMeta: metav1.ObjectMeta{Name: "staging", Namespace: ns},
+			Spec: v1.EnvironmentSpec{
+				Namespace: "jx-staging",
+				Kind:      v1.EnvironmentKindTypePermanent,
+			},
+		},
+		&v1.SourceRepository{
+			ObjectMeta: metav1.ObjectMeta{Name: "myorg-myapp", Namespace: ns},
+			Spec: v1.SourceRepositorySpec{
+				Org:  "myorg",
+				Repo: "myapp",
+			},
+		},
+	)
+	kubeClient := fake.NewSimpleClientset(newTestDeployment("myapp", "jx-staging", "1.2.3"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	w := NewWatcherWithOptions(&GetOptions{JXClient: jxClient, KubeClient: kubeClient, Namespace: ns, SkipRemote: true}, 0)
+	require.NoError(t, w.Start(ctx))
+
+	list, err := w.List()
+	require.NoError(t, err)
+	require.Len(t, list.Items[0].Environments["staging"].Deployments, 1)
+	assert.Empty(t, list.Items[0].Environments["staging"].Deployments[0].URL, "should not have an ingress yet")
+	drainChanges(w)
+
+	_, err = kubeClient.NetworkingV1().Ingresses("jx-staging").Create(context.TODO(), &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Name: "myapp", Namespace: "jx-staging"},
+		Spec: networkingv1.IngressSpec{
+			Rules: []networkingv1.IngressRule{{Host: "myapp.example.com"}},
+		},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	select {
+	case <-w.Changes():
+	case <-time.After(10 * time.Second):
+		require.Fail(t, "timed out waiting for a change notification")
+	}
+	list, err = w.List()
+	require.NoError(t, err)
+	assert.Equal(t, "http://myapp.example.com", list.Items[0].Environments["staging"].Deployments[0].URL)
+}
+
+func TestWatcherURLsInvalidatedByNamespace(t *testing.T) {
+	ns := "jx"
+	jxClient := fakejx.NewSimpleClientset(
+		&v1.Environment{
+			ObjectMeta: metav1.ObjectMeta{Name: "staging", Namespace: ns},
+			Spec: v1.EnvironmentSpec{
+				Namespace: "jx-staging",
+				Kind:      v1.EnvironmentKindTypePermanent,
+			},
+		},
+		&v1.SourceRepository{
+			ObjectMeta: metav1.ObjectMeta{Name: "myorg-myapp", Namespace: ns},
+			Spec: v1.SourceRepositorySpec{
+				Org:  "myorg",
+				Repo: "myapp",
+			},
+		},
+	)
+	kubeClient := fake.NewSimpleClientset(newTestDeployment("myapp", "jx-staging", "1.2.3"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	w := NewWatcherWithOptions(&GetOptions{JXClient: jxClient, KubeClient: kubeClient, Namespace: ns, SkipRemote: true}, 0)
+	require.NoError(t, w.Start(ctx))
+
+	_, err := w.List()
+	require.NoError(t, err)
+	w.lock.Lock()
+	assert.Contains(t, w.urls, "jx-staging/myapp", "should have cached the URL")
+	w.lock.Unlock()
+	drainChanges(w)
+
+	// an ingress whose name differs from the application may still expose it
+	_, err = kubeClient.NetworkingV1().Ingresses("jx-staging").Create(context.TODO(), &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Name: "myapp-ingress", Namespace: "jx-staging"},
+		Spec: networkingv1.IngressSpec{
+			Rules: []networkingv1.IngressRule{{Host: "myapp.example.com"}},
+		},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	select {
+	case <-w.Changes():
+	case <-time.After(10 * time.Second):
+		require.Fail(t, "timed out waiting for a change notification")
+	}
+	w.lock.Lock()
+	assert.NotContains(t, w.urls, "jx-staging/myapp", "should have invalidated the cached URL")
+	w.lock.Unlock()
+}
+
+func drainChanges(w *Watcher) {
+	for {
+		select {
+		case <-w.Changes():
+		default:
+			return
+		}
+	}
+}
+
+func newTestDeployment(name, ns, version string) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: ns,
+			Labels: map[string]string{
+				"version": version,
+			},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app": name,
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,246 @@
+package applications
+
+import (
+	"context"
+	"fmt"
+
+	v1 "github.com/jenkins-x/jx-api/v4/pkg/apis/jenkins.io/v1"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// KindDeployment the kind of a Deployment workload
+	KindDeployment = "Deployment"
+
+	// KindStatefulSet the kind of a StatefulSet workload
+	KindStatefulSet = "StatefulSet"
+
+	// KindDaemonSet the kind of a DaemonSet workload
+	KindDaemonSet = "DaemonSet"
+
+	// KindCronJob the kind of a CronJob workload
+	KindCronJob = "CronJob"
+)
+
+// Workload is the common view of the kubernetes resources which run the pods of an application
+type Workload struct {
+	// Kind the kind of the resource such as Deployment or StatefulSet
+	Kind string
+
+	// ObjectMeta the metadata of the resource
+	ObjectMeta *metav1.ObjectMeta
+
+	// AppLabels the labels used to find the app name, typically the pod selector
+	AppLabels map[string]string
+
+	// Pods the readiness of the pods of the resource
+	Pods string
+
+	// Version the version if it cannot be found from the labels of the resource
+	Version string
+
+	// VersionSource where the Version was found
+	VersionSource string
+
+	// Image the image of the primary container
+	Image string
+
+	// HelmRelease the latest revision of the helm release which created the resource if any
+	HelmRelease *HelmRelease
+
+	// DeployedHelmRelease the deployed revision of the helm release which created the resource if any
+	DeployedHelmRelease *HelmRelease
+
+	// URL the URL from the status of the resource. If blank the URL is found from the service or ingress
+	URL string
+
+	// Revision the latest ready revision of a serverless workload
+	Revision string
+
+	// Traffic how the traffic of a serverless workload is split between its revisions
+	Traffic []TrafficTarget
+
+	// Rollout the progressive delivery status of the workload
+	Rollout *RolloutStatus
+
+	// Auxiliary if the workload was created by a progressive delivery backend and should not be shown
+	Auxiliary bool
+}
+
+// NewDeploymentWorkload creates a workload from a Deployment
+func NewDeploymentWorkload(d *appsv1.Deployment) (*Workload, error) {
+	selector, err := metav1.LabelSelectorAsMap(d.Spec.Selector)
+	if err != nil {
+		return nil, err
+	}
+	return &Workload{
+		Kind:       KindDeployment,
+		ObjectMeta: &d.ObjectMeta,
+		AppLabels:  selector,
+		Pods:       Pods(d),
+		Image:      primaryImage(d.Name, &d.Spec.Template.Spec),
+	}, nil
+}
+
+// NewStatefulSetWorkload creates a workload from a StatefulSet
+func NewStatefulSetWorkload(s *appsv1.StatefulSet) (*Workload, error) {
+	selector, err := metav1.LabelSelectorAsMap(s.Spec.Selector)
+	if err != nil {
+		return nil, err
+	}
+	return &Workload{
+		Kind:       KindStatefulSet,
+		ObjectMeta: &s.ObjectMeta,
+		AppLabels:  selector,
+		Pods:       readyPods(s.Status.ReadyReplicas, s.Spec.Replicas),
+		Image:      primaryImage(s.Name, &s.Spec.Template.Spec),
+	}, nil
+}
+
+// NewDaemonSetWorkload creates a workload from a DaemonSet whose pods are ready out of those scheduled on nodes
+func NewDaemonSetWorkload(d *appsv1.DaemonSet) (*Workload, error) {
+	selector, err := metav1.LabelSelectorAsMap(d.Spec.Selector)
+	if err != nil {
+		return nil, err
+	}
+	desired := d.Status.DesiredNumberScheduled
+	return &Workload{
+		Kind:       KindDaemonSet,
+		ObjectMeta: &d.ObjectMeta,
+		AppLabels:  selector,
+		Pods:       readyPods(d.Status.NumberReady, &desired),
+		Image:      primaryImage(d.Name, &d.Spec.Template.Spec),
+	}, nil
+}
+
+// NewCronJobWorkload creates a workload from a CronJob. As a CronJob has no long running pods the number of active
+// jobs is used for the pods
+func NewCronJobWorkload(c *batchv1.CronJob) *Workload {
+	pods := ""
+	if len(c.Status.Active) > 0 {
+		pods = fmt.Sprintf("%d active", len(c.Status.Active))
+	}
+	return &Workload{
+		Kind:       KindCronJob,
+		ObjectMeta: &c.ObjectMeta,
+		AppLabels:  c.Spec.JobTemplate.Spec.Template.Labels,
+		Pods:       pods,
+		Image:      primaryImage(c.Name, &c.Spec.JobTemplate.Spec.Template.Spec),
+	}
+}
+
+// readyPods returns the ratio of ready pods to replicas or blank if none are ready
+func readyPods(ready int32, replicas *int32) string {
+	if replicas == nil || ready <= 0 {
+		return ""
+	}
+	return int32ToA(ready) + "/" + int32ToA(*replicas)
+}
+
+// CreateWorkloadDeployment creates the application deployment of the workload in the environment
+func CreateWorkloadDeployment(w *Workload, env *v1.Environment) Deployment {
+	answer := Deployment{
+		Name:     GetAppName(w.ObjectMeta.Name, w.ObjectMeta.Namespace),
+		Kind:     w.Kind,
+		Workload: w.ObjectMeta.Name,
+		Pods:     w.Pods,
+		URL:      w.URL,
+		Canary:   w.Auxiliary,
+		Revision: w.Revision,
+		Traffic:  w.Traffic,
+		Rollout:  w.Rollout,
+		Image:    w.Image,
+		Helm:     w.HelmRelease,
+		Metadata: matchingMetadata(w.ObjectMeta, w.AppLabels),
+	}
+	answer.Version, answer.VersionSource = w.version()
+	depAppName := GetAppName(w.AppLabels["app"], env.Spec.Namespace)
+	if depAppName != "" {
+		answer.Name = depAppName
+	}
+	return answer
+}
+
+// hasServiceURL returns true if the URL of the workload should be found from its service or ingress
+func (w *Workload) hasServiceURL() bool {
+	return w.URL == "" && (w.Kind == KindDeployment || w.Kind == KindStatefulSet || w.Kind == KindDaemonSet || w.Kind == KindRollout)
+}
+
+// workloadKey returns the key of a workload in the deployments of a namespace
+func workloadKey(kind, name string) string {
+	if kind == KindDeployment {
+		return name
+	}
+	return kind + "/" + name
+}
+
+// getWorkloads returns the workloads of every supported kind in the namespace. Only the Deployments are required to
+// be visible, the other kinds are skipped if the user is not allowed to list them
+func getWorkloads(ctx context.Context, kubeClient kubernetes.Interface, ns, selector string) ([]*Workload, error) {
+	listOptions := metav1.ListOptions{LabelSelector: selector}
+	var answer []*Workload
+	deps, err := kubeClient.AppsV1().Deployments(ns).List(ctx, listOptions)
+	if err != nil {
+		return nil, err
+	}
+	for i := range deps.Items {
+		w, err := NewDeploymentWorkload(&deps.Items[i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to create workload for Deployment %s in namespace %s: %w", deps.Items[i].Name, ns, err)
+		}
+		answer = append(answer, w)
+	}
+
+	statefulSets, err := kubeClient.AppsV1().StatefulSets(ns).List(ctx, listOptions)
+	if err != nil && !skipWorkloadError(KindStatefulSet, ns, err) {
+		return nil, err
+	}
+	if statefulSets != nil {
+		for i := range statefulSets.Items {
+			w, err := NewStatefulSetWorkload(&statefulSets.Items[i])
+			if err != nil {
+				return nil, fmt.Errorf("failed to create workload for StatefulSet %s in namespace %s: %w", statefulSets.Items[i].Name, ns, err)
+			}
+			answer = append(answer, w)
+		}
+	}
+
+	daemonSets, err := kubeClient.AppsV1().DaemonSets(ns).List(ctx, listOptions)
+	if err != nil && !skipWorkloadError(KindDaemonSet, ns, err) {
+		return nil, err
+	}
+	if daemonSets != nil {
+		for i := range daemonSets.Items {
+			w, err := NewDaemonSetWorkload(&daemonSets.Items[i])
+			if err != nil {
+				return nil, fmt.Errorf("failed to create workload for DaemonSet %s in namespace %s: %w", daemonSets.Items[i].Name, ns, err)
+			}
+			answer = append(answer, w)
+		}
+	}
+
+	cronJobs, err := kubeClient.BatchV1().CronJobs(ns).List(ctx, listOptions)
+	if err != nil && !skipWorkloadError(KindCronJob, ns, err) {
+		return nil, err
+	}
+	if cronJobs != nil {
+		for i := range cronJobs.Items {
+			answer = append(answer, NewCronJobWorkload(&cronJobs.Items[i]))
+		}
+	}
+	return answer, nil
+}
+
+// skipWorkloadError returns true if the error listing an optional workload kind should be ignored
+func skipWorkloadError(kind, ns string, err error) bool {
+	if apierrors.IsForbidden(err) || apierrors.IsNotFound(err) {
+		log.Logger().Debugf("skipping workloads of kind %s in namespace %s: %s", kind, ns, err.Error())
+		return true
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package applications
+
+import (
+	"context"
+	"testing"
+
+	v1 "github.com/jenkins-x/jx-api/v4/pkg/apis/jenkins.io/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestGetDeploymentsWorkloadKinds(t *testing.T) {
+	ns := "jx-staging"
+	replicas := int32(3)
+	selector := func(name string) *metav1.LabelSelector {
+		return &metav1.LabelSelector{MatchLabels: map[string]string{"app": name}}
+	}
+	deployment := newTestDeployment("web", ns, "1.0.0")
+	deployment.Spec.Replicas = &replicas
+	deployment.Status.ReadyReplicas = 2
+	kubeClient := fake.NewSimpleClientset(
+		deployment,
+		&appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: ns, Labels: map[string]string{"version": "2.0.0"}},
+			Spec:       appsv1.StatefulSetSpec{Replicas: &replicas, Selector: selector("db")},
+			Status:     appsv1.StatefulSetStatus{ReadyReplicas: 3},
+		},
+		&appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: ns, Labels: map[string]string{"version": "3.0.0"}},
+			Spec:       appsv1.DaemonSetSpec{Selector: selector("agent")},
+			Status:     appsv1.DaemonSetStatus{DesiredNumberScheduled: 5, NumberReady: 4},
+		},
+		&batchv1.CronJob{
+			ObjectMeta: metav1.ObjectMeta{Name: "report", Namespace: ns, Labels: map[string]string{"version": "4.0.0"}},
+			Spec: batchv1.CronJobSpec{
+				JobTemplate: batchv1.JobTemplateSpec{
+					Spec: batchv1.JobSpec{
+						Template: corev1.PodTemplateSpec{
+							ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "report"}},
+						},
+					},
+				},
+			},
+			Status: batchv1.CronJobStatus{Active: []corev1.ObjectReference{{Name: "report-1"}}},
+		},
+	)
+	env := &v1.Environment{
+		ObjectMeta: metav1.ObjectMeta{Name: "staging"},
+		Spec:       v1.EnvironmentSpec{Namespace: ns},
+	}
+
+	deployments, err := getDeployments(context.TODO(), kubeClient, nil, nil, ns, env, "")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Deployment{
+		"web":             {Name: "web", Kind: KindDeployment, Workload: "web", Pods: "2/3", Version: "1.0.0", VersionSource: "label:version"},
+		"StatefulSet/db":  {Name: "db", Kind: KindStatefulSet, Workload: "db", Pods: "3/3", Version: "2.0.0", VersionSource: "label:version"},
+		"DaemonSet/agent": {Name: "agent", Kind: KindDaemonSet, Workload: "agent", Pods: "4/5", Version: "3.0.0", VersionSource: "label:version"},
+		"CronJob/report":  {Name: "report", Kind: KindCronJob, Workload: "report", Pods: "1 active", Version: "4.0.0", VersionSource: "label:version"},
+	}, deployments)
+
+	// kinds the user cannot list are skipped
+	kubeClient.PrependReactor("list", "cronjobs", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(schema.GroupResource{Group: "batch", Resource: "cronjobs"}, "", nil)
+	})
+	deployments, err = getDeployments(context.TODO(), kubeClient, nil, nil, ns, env, "")
+	require.NoError(t, err)
+	assert.Len(t, deployments, 3)
+	assert.NotContains(t, deployments, "CronJob/report")
+}
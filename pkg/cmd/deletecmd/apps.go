@@ -0,0 +1,155 @@
+package deletecmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jenkins-x/go-scm/scm"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// App an application to remove identified by the owner and name of its git repository
+type App struct {
+	Owner      string
+	Repository string
+}
+
+// String returns the owner and repository of the app
+func (a App) String() string {
+	if a.Owner == "" {
+		return a.Repository
+	}
+	return scm.Join(a.Owner, a.Repository)
+}
+
+// Removal the changes made to the cluster git repository to remove an app
+type Removal struct {
+	App App
+
+	// SourceConfig whether the repository was removed from the source config
+	SourceConfig bool
+
+	// Namespaces the namespaces whose helmfiles contained the chart of the app. Nil if the chart was removed by the jx
+	// binary in which case the namespaces are not known
+	Namespaces []string
+}
+
+// String describes the removal for the Pull Request body
+func (r *Removal) String() string {
+	text := "`" + r.App.String() + "`"
+	switch len(r.Namespaces) {
+	case 0:
+		if r.Namespaces == nil {
+			text += " from the helmfiles"
+		} else {
+			text += " which is not deployed in any namespace"
+		}
+	case 1:
+		text += " from namespace " + r.Namespaces[0]
+	default:
+		text += " from namespaces " + strings.Join(r.Namespaces, ", ")
+	}
+	if r.SourceConfig {
+		text += " and the source config"
+	}
+	return text
+}
+
+// ParseApp parses an app from either 'owner/repo' or 'repo' in which case the default owner is used. An error is
+// returned if the owner conflicts with the default owner
+func ParseApp(text, defaultOwner string) (App, error) {
+	text = strings.TrimSpace(text)
+	owner, repo, found := strings.Cut(text, "/")
+	if !found {
+		owner, repo = defaultOwner, text
+	}
+	if repo == "" || strings.Contains(repo, "/") {
+		return App{}, fmt.Errorf("invalid app %q should be 'owner/repo' or 'repo'", text)
+	}
+	if defaultOwner != "" && owner != defaultOwner {
+		return App{}, fmt.Errorf("app %q conflicts with the owner %s", text, defaultOwner)
+	}
+	return App{Owner: owner, Repository: repo}, nil
+}
+
+// LoadAppsFile loads the apps listed in a file with an 'owner/repo' or 'repo' per line. Blank lines and lines
+// starting with '#' are ignored
+func LoadAppsFile(path, defaultOwner string) ([]App, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file %s: %w", path, err)
+	}
+	defer f.Close()
+
+	var answer []App
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		app, err := ParseApp(text, defaultOwner)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse line %d of file %s: %w", line, path, err)
+		}
+		answer = append(answer, app)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read file %s: %w", path, err)
+	}
+	return answer, nil
+}
+
+// resolveApps adds the apps of the --repo flags, the --file and the SourceRepositories matching the --selector to
+// the apps, ignoring duplicates. SourceRepositories of other owners are ignored if the --owner is specified
+func (o *Options) resolveApps(ctx context.Context) error {
+	apps := o.Apps
+	for _, text := range o.Repositories {
+		app, err := ParseApp(text, o.Owner)
+		if err != nil {
+			return err
+		}
+		apps = append(apps, app)
+	}
+	if o.File != "" {
+		fileApps, err := LoadAppsFile(o.File, o.Owner)
+		if err != nil {
+			return err
+		}
+		apps = append(apps, fileApps...)
+	}
+	if o.Selector != "" {
+		list, err := o.JXClient.JenkinsV1().SourceRepositories(o.Namespace).List(ctx, metav1.ListOptions{LabelSelector: o.Selector})
+		if err != nil {
+			return fmt.Errorf("failed to list SourceRepositories in namespace %s with selector %s: %w", o.Namespace, o.Selector, err)
+		}
+		matched := false
+		for i := range list.Items {
+			sr := &list.Items[i]
+			if o.Owner != "" && sr.Spec.Org != o.Owner {
+				continue
+			}
+			apps = append(apps, App{Owner: sr.Spec.Org, Repository: sr.Spec.Repo})
+			matched = true
+		}
+		if !matched {
+			if o.Owner != "" {
+				return fmt.Errorf("no SourceRepositories of owner %s in namespace %s match selector %s", o.Owner, o.Namespace, o.Selector)
+			}
+			return fmt.Errorf("no SourceRepositories in namespace %s match selector %s", o.Namespace, o.Selector)
+		}
+	}
+
+	o.Apps = nil
+	found := map[App]bool{}
+	for _, app := range apps {
+		if !found[app] {
+			found[app] = true
+			o.Apps = append(o.Apps, app)
+		}
+	}
+	return nil
+}
@@ -3,16 +3,27 @@ package deletecmd
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/jenkins-x-plugins/jx-application/pkg/applications"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/apis/gitops/v1alpha1"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/sourceconfigs"
 	"github.com/jenkins-x-plugins/jx-promote/pkg/environments"
 	"github.com/jenkins-x/go-scm/scm"
 	jxc "github.com/jenkins-x/jx-api/v4/pkg/client/clientset/versioned"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/templates"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/input"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/kube"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/kube/jxclient"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/kube/jxenv"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/options"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/yamls"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
 
 	"k8s.io/client-go/kubernetes"
 
@@ -26,16 +37,29 @@ type Options struct {
 
 	GitURL           string
 	EnvironmentName  string
+	AllEnvs          bool
 	AutoMerge        bool
+	DryRun           bool
 	PullRequestTitle string
 	PullRequestBody  string
 	NoSourceConfig   bool
+	ShellOut         bool
+	Yes              bool
+	SkipRemote       bool
+	NoCache          bool
 	Owner            string
-	Repository       string
+	Repositories     []string
+	File             string
+	Selector         string
+	Apps             []App
+	Removals         []Removal
+	Targets          []*Target
 	RemoveNamespace  string
 	Namespace        string
 	KubeClient       kubernetes.Interface
 	JXClient         jxc.Interface
+	Input            input.Interface
+	RemoteCache      *applications.RemoteCache
 }
 
 var (
@@ -44,20 +68,58 @@ var (
 
 		This command actually create a Pull Request on the development cluster git repository so you can review the changes to be made.
 
+		Several apps can be removed in a single commit and Pull Request by repeating --repo, listing them in a --file or
+		selecting their SourceRepositories with --selector. The Pull Request body lists each app and the namespaces it
+		was removed from.
+
+		The helmfiles and source config are edited in-process so the jx binary is not required. Use --shell-out to
+		invoke 'jx gitops' instead.
+
+		Use --all-envs to remove the apps from the git repository of the development environment and every permanent
+		environment, including remote clusters, in one go. One Pull Request is created per git repository and a summary of
+		the Pull Requests and their merge status is displayed. The source config is only changed in repositories which
+		are not exclusively used by remote clusters.
+
+		If no apps are specified and a terminal is attached the apps can be picked from the applications in the cluster.
+		When running in a terminal the name of each app must be typed to confirm its removal unless --yes is specified.
+
+		Use --dry-run to print the diff of the changes without pushing them or creating a Pull Request.
+
 `)
 
 	cmdExample = templates.Examples(`
+		# picks the applications to delete from the development cluster
+		jx application delete
+
 		# deletes the application with the given name from the development cluster
 		jx application delete --repo myapp
 
+		# deletes the application without confirming its name for use in automation
+		jx application delete --repo myapp --yes
+
 		# deletes the deployed application for the remote production cluster only
 		jx application delete --repo myapp --env production
 
+		# deletes the deployed application from every environment including remote clusters
+		jx application delete --repo myapp --all-envs
+
 		# deletes the application with the given name with the git owner 
 		jx application delete --repo myapp --owner myorg
 
 		# deletes the deployed applications but doesn't remove the '.jx/gitops/source-config.yaml' entry - so new releases come back
 		jx application delete --repo myapp --owner myorg --no-source
+
+		# deletes several applications in one Pull Request
+		jx application delete --repo myorg/cheese --repo myorg/wine
+
+		# deletes the applications listed as 'owner/repo' per line in a file
+		jx application delete --file apps.txt
+
+		# deletes the applications whose SourceRepositories have the given label
+		jx application delete --selector product=legacy
+
+		# shows the changes which would be made to the cluster git repository without creating a Pull Request
+		jx application delete --repo myapp --dry-run
 `)
 )
 
@@ -76,10 +138,16 @@ func NewCmdDelete() (*cobra.Command, *Options) {
 
 	cmd.Flags().StringVarP(&o.GitURL, "url", "u", "", "The git URL of the cluster git repository to modify")
 	cmd.Flags().StringVarP(&o.EnvironmentName, "env", "e", "dev", "The Environment name used to find the repository git URL if none is specified")
+	cmd.Flags().BoolVarP(&o.AllEnvs, "all-envs", "", false, "Remove the app from the git repositories of all the permanent environments, creating a Pull Request per repository")
 	cmd.Flags().BoolVarP(&o.AutoMerge, "auto-merge", "", true, "should we automatically merge if the PR pipeline is green")
 	cmd.Flags().StringVar(&o.PullRequestTitle, "pull-request-title", "", "the PR title")
 	cmd.Flags().StringVar(&o.PullRequestBody, "pull-request-body", "", "the PR body")
 
+	cmd.Flags().BoolVarP(&o.DryRun, "dry-run", "", false, "Clone the cluster git repository and print the diff of removing the app without pushing the changes or creating a Pull Request")
+	cmd.Flags().BoolVarP(&o.ShellOut, "shell-out", "", false, "Remove the app by invoking the 'jx gitops' binary rather than editing the files in-process")
+	cmd.Flags().BoolVarP(&o.Yes, "yes", "y", false, "Remove the apps without typing their names to confirm")
+	cmd.Flags().BoolVarP(&o.SkipRemote, "skip-remote", "", false, "Do not fetch the git repositories of remote environments when picking the apps to remove")
+	cmd.Flags().BoolVarP(&o.NoCache, "no-cache", "", false, "Clone the git repositories of remote environments into temporary directories rather than reusing cached clones when picking the apps to remove")
 	cmd.Flags().BoolVarP(&o.NoSourceConfig, "no-source", "", false, "Do not remove the repository from the '.jx/gitops/source-config/yaml' file - so that a new release will come back")
 
 	o.EnvironmentPullRequestOptions.ScmClientFactory.AddFlags(cmd)
@@ -89,7 +157,9 @@ func NewCmdDelete() (*cobra.Command, *Options) {
 	cmd.Flags().StringVarP(&eo.CommitMessage, "commit-message", "", "", "the commit message")
 
 	cmd.Flags().StringVarP(&o.Owner, "owner", "o", "", "The name of the git organisation or user which owns the app")
-	cmd.Flags().StringVarP(&o.Repository, "repo", "r", "", "The name of the repository to remove")
+	cmd.Flags().StringArrayVarP(&o.Repositories, "repo", "r", nil, "The name of the repository to remove as 'repo' or 'owner/repo'. May be repeated to remove several apps")
+	cmd.Flags().StringVarP(&o.File, "file", "f", "", "A file listing the repositories to remove as 'repo' or 'owner/repo' per line")
+	cmd.Flags().StringVarP(&o.Selector, "selector", "l", "", "The label selector of the SourceRepositories to remove. Only those of the --owner are removed if it is specified")
 	cmd.Flags().StringVarP(&o.RemoveNamespace, "remove-ns", "", "", "The namespace to remove the app from. If blank remove from all deployed namespaces")
 
 	o.BaseOptions.AddBaseFlags(cmd)
@@ -101,11 +171,15 @@ func NewCmdDelete() (*cobra.Command, *Options) {
 func (o *Options) Validate() error {
 	var err error
 
-	if o.Repository == "" {
+	pick := len(o.Apps) == 0 && len(o.Repositories) == 0 && o.File == "" && o.Selector == ""
+	if pick && !o.interactive() {
 		return options.MissingOption("repo")
 	}
+	if o.AllEnvs && o.GitURL != "" {
+		return fmt.Errorf("cannot specify both --all-envs and --url")
+	}
 
-	if o.GitURL == "" {
+	if o.GitURL == "" || o.Selector != "" || pick {
 		o.KubeClient, o.Namespace, err = kube.LazyCreateKubeClientAndNamespace(o.KubeClient, o.Namespace)
 		if err != nil {
 			return fmt.Errorf("failed to create kube client: %w", err)
@@ -121,7 +195,24 @@ func (o *Options) Validate() error {
 		if ns != "" {
 			o.Namespace = ns
 		}
+	}
 
+	if pick {
+		err = o.pickApps()
+	} else {
+		err = o.resolveApps(o.GetContext())
+	}
+	if err != nil {
+		return fmt.Errorf("failed to find the apps to remove: %w", err)
+	}
+
+	if o.AllEnvs {
+		o.Targets, err = o.environmentTargets()
+		if err != nil {
+			return err
+		}
+	} else if o.GitURL == "" {
+		ns := o.Namespace
 		env, err := jxenv.GetEnvironment(o.JXClient, ns, o.EnvironmentName)
 		if err != nil {
 			return fmt.Errorf("failed to find Environment %s in namespace %s: %w", o.EnvironmentName, ns, err)
@@ -138,6 +229,9 @@ func (o *Options) Validate() error {
 
 	// lazy create git
 	o.EnvironmentPullRequestOptions.Git()
+	if o.Out == nil {
+		o.Out = os.Stdout
+	}
 	return nil
 }
 
@@ -148,8 +242,34 @@ func (o *Options) Run() error {
 		return fmt.Errorf("failed to validate options: %w", err)
 	}
 
+	if !o.Yes && !o.DryRun && o.interactive() {
+		err = o.confirmApps()
+		if err != nil {
+			return err
+		}
+	}
+
+	if o.AllEnvs {
+		return o.deleteFromAllEnvironments()
+	}
+	if o.DryRun {
+		return o.DryRunDelete()
+	}
+	_, err = o.createPullRequest()
+	return err
+}
+
+// createPullRequest creates a Pull Request removing the apps from the git repository
+func (o *Options) createPullRequest() (*scm.PullRequest, error) {
 	if o.PullRequestTitle == "" {
-		o.PullRequestTitle = fmt.Sprintf("fix: remove app %s", o.AppDescription())
+		switch {
+		case len(o.Apps) == 1:
+			o.PullRequestTitle = fmt.Sprintf("fix: remove app %s", o.AppDescription())
+		case len(o.Apps) <= 3:
+			o.PullRequestTitle = fmt.Sprintf("fix: remove apps %s", o.AppDescription())
+		default:
+			o.PullRequestTitle = fmt.Sprintf("fix: remove %d apps", len(o.Apps))
+		}
 	}
 	if o.CommitTitle == "" {
 		o.CommitTitle = o.PullRequestTitle
@@ -157,30 +277,162 @@ func (o *Options) Run() error {
 
 	o.Function = func() error {
 		dir := o.OutDir
-		return o.DeleteApp(dir)
+		err := o.DeleteApp(dir)
+		if err != nil {
+			return err
+		}
+		// the commit message is used as the body of the Pull Request
+		if o.CommitMessage == "" {
+			o.CommitMessage = o.PullRequestBodyText()
+		}
+		return nil
 	}
 
-	_, err = o.EnvironmentPullRequestOptions.Create(o.GitURL, "", o.Labels, o.AutoMerge)
+	pr, err := o.EnvironmentPullRequestOptions.Create(o.GitURL, "", o.Labels, o.AutoMerge)
 	if err != nil {
-		return fmt.Errorf("failed to create Pull Request on repository %s: %w", o.GitURL, err)
+		return nil, fmt.Errorf("failed to create Pull Request on repository %s: %w", o.GitURL, err)
 	}
-	return nil
+	return pr, nil
 }
 
-// AppDescription returns the app description
+// DryRunDelete clones the cluster git repository into a temporary directory, removes the app then writes the unified
+// diff of the changes without pushing them or creating a Pull Request
+func (o *Options) DryRunDelete() error {
+	g := o.EnvironmentPullRequestOptions.Git()
+	cloneGitURL := o.GitURL
+	if o.ScmClientFactory.GitToken != "" && o.ScmClientFactory.GitUsername != "" {
+		var err error
+		cloneGitURL, err = o.ScmClientFactory.CreateAuthenticatedURL(cloneGitURL)
+		if err != nil {
+			return fmt.Errorf("failed to create authenticated git URL to clone with for private repositories: %w", err)
+		}
+	}
+	dir, err := gitclient.CloneToDir(g, cloneGitURL, "")
+	if err != nil {
+		return fmt.Errorf("failed to clone git URL %s: %w", o.GitURL, err)
+	}
+	defer os.RemoveAll(dir)
+
+	if o.BaseBranchName != "" {
+		err = gitclient.CheckoutRemoteBranch(g, dir, o.BaseBranchName)
+		if err != nil {
+			return fmt.Errorf("failed to checkout remote branch %s from %s: %w", o.BaseBranchName, o.GitURL, err)
+		}
+	}
+
+	err = o.DeleteApp(dir)
+	if err != nil {
+		return fmt.Errorf("failed to remove apps %s: %w", o.AppDescription(), err)
+	}
+
+	// lets stage everything so that added and removed files are included in the diff
+	_, err = g.Command(dir, "add", "--all")
+	if err != nil {
+		return fmt.Errorf("failed to stage the changes in %s: %w", dir, err)
+	}
+	diff, err := g.Command(dir, "diff", "--cached", "--no-color", "--no-ext-diff")
+	if err != nil {
+		return fmt.Errorf("failed to diff the changes in %s: %w", dir, err)
+	}
+	if diff == "" {
+		log.Logger().Infof("removing apps %s would not change repository %s", termcolor.ColorInfo(o.AppDescription()), termcolor.ColorInfo(o.GitURL))
+		return nil
+	}
+	_, err = fmt.Fprintln(o.Out, diff)
+	return err
+}
+
+// AppDescription returns the description of the apps to remove
 func (o *Options) AppDescription() string {
-	if o.Owner == "" {
-		return o.Repository
+	names := make([]string, 0, len(o.Apps))
+	for _, app := range o.Apps {
+		names = append(names, app.String())
+	}
+	return strings.Join(names, ", ")
+}
+
+// PullRequestBodyText returns the Pull Request body followed by a line for each app removed
+func (o *Options) PullRequestBodyText() string {
+	var sb strings.Builder
+	if o.PullRequestBody != "" {
+		sb.WriteString(strings.TrimSpace(o.PullRequestBody))
+		sb.WriteString("\n\n")
+	}
+	sb.WriteString("Removes the following apps:\n\n")
+	for i := range o.Removals {
+		sb.WriteString("* ")
+		sb.WriteString(o.Removals[i].String())
+		sb.WriteString("\n")
 	}
-	return scm.Join(o.Owner, o.Repository)
+	return sb.String()
 }
 
+// DeleteApp removes the apps from the source config and the helmfiles in the cluster git repository checked out in
+// the directory recording each removal. The files are edited in-process unless ShellOut is enabled
 func (o *Options) DeleteApp(dir string) error {
+	o.Removals = nil
+	for _, app := range o.Apps {
+		r := Removal{App: app}
+		var err error
+		if o.ShellOut {
+			err = o.deleteAppWithBinary(dir, app)
+			if err != nil {
+				return err
+			}
+			r.SourceConfig = !o.NoSourceConfig
+		} else {
+			if !o.NoSourceConfig {
+				r.SourceConfig, err = o.removeSourceConfig(dir, app)
+				if err != nil {
+					return err
+				}
+			}
+			r.Namespaces, err = o.removeCharts(dir, app)
+			if err != nil {
+				return err
+			}
+		}
+		o.Removals = append(o.Removals, r)
+	}
+	return nil
+}
+
+// removeSourceConfig removes the repository from the '.jx/gitops/source-config.yaml' file if it exists returning
+// whether it was removed
+func (o *Options) removeSourceConfig(dir string, app App) (bool, error) {
+	path := filepath.Join(dir, ".jx", "gitops", v1alpha1.SourceConfigFileName)
+	exists, err := files.FileExists(path)
+	if err != nil {
+		return false, fmt.Errorf("failed to check if file exists %s: %w", path, err)
+	}
+	if !exists {
+		log.Logger().Infof("file %s does not exist", termcolor.ColorStatus(path))
+		return false, nil
+	}
+	config := &v1alpha1.SourceConfig{}
+	err = yamls.LoadFile(path, config)
+	if err != nil {
+		return false, fmt.Errorf("failed to load file %s: %w", path, err)
+	}
+	if !sourceconfigs.RemoveRepository(config, app.Owner, app.Repository) {
+		log.Logger().Infof("repository %s not found in file %s", termcolor.ColorInfo(app.String()), termcolor.ColorInfo(path))
+		return false, nil
+	}
+	err = yamls.SaveFile(config, path)
+	if err != nil {
+		return false, fmt.Errorf("failed to save file %s: %w", path, err)
+	}
+	log.Logger().Infof("removed repository %s from file %s", termcolor.ColorInfo(app.String()), termcolor.ColorInfo(path))
+	return true, nil
+}
+
+// deleteAppWithBinary removes the app by invoking the jx gitops binary
+func (o *Options) deleteAppWithBinary(dir string, app App) error {
 	if !o.NoSourceConfig {
 		// lets remove the source config
-		args := []string{"gitops", "repository", "delete", "--name", o.Repository}
-		if o.Owner != "" {
-			args = append(args, "--owner", o.Owner)
+		args := []string{"gitops", "repository", "delete", "--name", app.Repository}
+		if app.Owner != "" {
+			args = append(args, "--owner", app.Owner)
 		}
 
 		c := &cmdrunner.Command{
@@ -197,7 +449,7 @@ func (o *Options) DeleteApp(dir string) error {
 	}
 
 	// now lets remove the promoted charts
-	args := []string{"gitops", "helmfile", "delete", "--chart", o.Repository}
+	args := []string{"gitops", "helmfile", "delete", "--chart", app.Repository}
 	if o.RemoveNamespace != "" {
 		args = append(args, "--namespace", o.RemoveNamespace)
 	}
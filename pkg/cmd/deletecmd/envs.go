@@ -0,0 +1,227 @@
+package deletecmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jenkins-x/go-scm/scm"
+	v1 "github.com/jenkins-x/jx-api/v4/pkg/apis/jenkins.io/v1"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/giturl"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/kube/jxenv"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/scmhelpers"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/table"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+)
+
+const (
+	// TargetStatusMerged the Pull Request has been merged
+	TargetStatusMerged = "merged"
+
+	// TargetStatusOpen the Pull Request is open and waiting to be merged
+	TargetStatusOpen = "open"
+
+	// TargetStatusClosed the Pull Request was closed without being merged
+	TargetStatusClosed = "closed"
+
+	// TargetStatusNoChanges the apps were not found in the git repository so no Pull Request was created
+	TargetStatusNoChanges = "no changes"
+
+	// TargetStatusFailed the apps could not be removed from the git repository
+	TargetStatusFailed = "failed"
+)
+
+// Target a git repository shared by one or more environments which the apps are removed from
+type Target struct {
+	GitURL       string
+	Environments []string
+
+	// Remote whether all the environments are in remote clusters in which case the source config is left alone
+	Remote bool
+
+	Removals    []Removal
+	PullRequest *scm.PullRequest
+	Error       error
+}
+
+// Status returns the merge status of the Pull Request created for the target as of when it was last looked up
+func (t *Target) Status() string {
+	switch {
+	case t.Error != nil:
+		return TargetStatusFailed
+	case t.PullRequest == nil:
+		return TargetStatusNoChanges
+	case t.PullRequest.Merged:
+		return TargetStatusMerged
+	case t.PullRequest.Closed:
+		return TargetStatusClosed
+	default:
+		return TargetStatusOpen
+	}
+}
+
+// environmentTargets returns the git repositories of the development and permanent environments in order. Environments
+// which share a git repository, such as a local staging environment and the dev cluster, are removed in one Pull Request
+func (o *Options) environmentTargets() ([]*Target, error) {
+	envMap, names, err := jxenv.GetOrderedEnvironments(o.JXClient, o.Namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load Environments in namespace %s: %w", o.Namespace, err)
+	}
+	var answer []*Target
+	targets := map[string]*Target{}
+	for _, name := range names {
+		env := envMap[name]
+		if env.Spec.Kind != v1.EnvironmentKindTypeDevelopment && env.Spec.Kind != v1.EnvironmentKindTypePermanent {
+			continue
+		}
+		gitURL := env.Spec.Source.URL
+		if gitURL == "" {
+			log.Logger().Warnf("ignoring Environment %s as it has no git URL", termcolor.ColorInfo(name))
+			continue
+		}
+		t := targets[gitURL]
+		if t == nil {
+			t = &Target{GitURL: gitURL, Remote: true}
+			targets[gitURL] = t
+			answer = append(answer, t)
+		}
+		t.Environments = append(t.Environments, name)
+		t.Remote = t.Remote && env.Spec.RemoteCluster
+	}
+	if len(answer) == 0 {
+		return nil, fmt.Errorf("no Environments with a git URL in namespace %s", o.Namespace)
+	}
+	return answer, nil
+}
+
+// deleteFromAllEnvironments removes the apps from the git repository of every environment with one Pull Request per
+// repository, carrying on if a repository fails, then renders a summary of the current state of the Pull Requests
+func (o *Options) deleteFromAllEnvironments() error {
+	flagsFactory := o.ScmClientFactory
+	factories := map[string]scmhelpers.Factory{}
+	noSourceConfig := o.NoSourceConfig
+	commitMessage := o.CommitMessage
+	baseBranchName := o.BaseBranchName
+	branchName := o.BranchName
+	failed := 0
+	for _, t := range o.Targets {
+		// lets reset the state which creating a Pull Request on the previous repository changed
+		o.GitURL = t.GitURL
+		o.NoSourceConfig = noSourceConfig || t.Remote
+		o.CommitMessage = commitMessage
+		o.BaseBranchName = baseBranchName
+		o.BranchName = branchName
+		serverURL, err := gitServerURL(t.GitURL)
+		if err != nil {
+			t.Error = err
+			failed++
+			log.Logger().Errorf("failed to remove apps from repository %s: %s", t.GitURL, t.Error.Error())
+			continue
+		}
+		o.ScmClientFactory = serverScmClientFactory(factories, &flagsFactory, serverURL)
+
+		log.Logger().Infof("removing apps %s from environments %s in repository %s", termcolor.ColorInfo(o.AppDescription()), termcolor.ColorInfo(strings.Join(t.Environments, ", ")), termcolor.ColorInfo(t.GitURL))
+		if o.DryRun {
+			t.Error = o.DryRunDelete()
+		} else {
+			t.PullRequest, t.Error = o.createPullRequest()
+		}
+		t.Removals = o.Removals
+		factories[serverURL] = o.ScmClientFactory
+		if t.Error != nil {
+			failed++
+			log.Logger().Errorf("failed to remove apps from repository %s: %s", t.GitURL, t.Error.Error())
+		}
+	}
+	if !o.DryRun {
+		o.refreshPullRequests(factories)
+		o.renderTargets()
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to remove apps %s from %d of %d environment repositories", o.AppDescription(), failed, len(o.Targets))
+	}
+	return nil
+}
+
+// serverScmClientFactory returns the factory of the git server so that the git token of one git server is never used
+// for another. The factory configured by the flags is only used for the --git-server, or every git server if it
+// is not specified
+func serverScmClientFactory(factories map[string]scmhelpers.Factory, flagsFactory *scmhelpers.Factory, serverURL string) scmhelpers.Factory {
+	if f, ok := factories[serverURL]; ok {
+		return f
+	}
+	f := *flagsFactory
+	if f.GitServerURL != "" && f.GitServerURL != serverURL {
+		f.GitServerURL = ""
+		f.GitKind = ""
+		f.GitUsername = ""
+		f.GitToken = ""
+		f.ScmClient = nil
+	}
+	return f
+}
+
+// gitServerURL returns the URL of the git server hosting the git repository
+func gitServerURL(gitURL string) (string, error) {
+	gitInfo, err := giturl.ParseGitURL(gitURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse git URL %s: %w", gitURL, err)
+	}
+	return gitInfo.HostURLWithoutUser(), nil
+}
+
+// refreshPullRequests looks up the Pull Requests again so that the summary shows whether they have been merged or
+// closed since they were created, such as by --auto-merge, rather than their state at creation
+func (o *Options) refreshPullRequests(factories map[string]scmhelpers.Factory) {
+	ctx := o.GetContext()
+	for _, t := range o.Targets {
+		if t.PullRequest == nil || t.Error != nil {
+			continue
+		}
+		gitInfo, err := giturl.ParseGitURL(t.GitURL)
+		if err != nil {
+			continue
+		}
+		scmClient := factories[gitInfo.HostURLWithoutUser()].ScmClient
+		if scmClient == nil {
+			continue
+		}
+		pr, _, err := scmClient.PullRequests.Find(ctx, scm.Join(gitInfo.Organisation, gitInfo.Name), t.PullRequest.Number)
+		if err != nil {
+			log.Logger().Warnf("failed to refresh Pull Request %s: %s", t.PullRequest.Link, err.Error())
+			continue
+		}
+		if pr.Link == "" {
+			pr.Link = t.PullRequest.Link
+		}
+		t.PullRequest = pr
+	}
+}
+
+// renderTargets renders a summary table of the Pull Request created for each environment repository
+func (o *Options) renderTargets() {
+	t := table.CreateTable(o.Out)
+	t.AddRow("ENVIRONMENTS", "REPOSITORY", "PULL REQUEST", "STATUS")
+	for _, target := range o.Targets {
+		link := ""
+		if target.PullRequest != nil {
+			link = target.PullRequest.Link
+		}
+		t.AddRow(strings.Join(target.Environments, ", "), target.GitURL, link, statusCell(target.Status()))
+	}
+	t.Render()
+}
+
+// statusCell highlights the repositories which have not been merged
+func statusCell(status string) string {
+	switch status {
+	case TargetStatusFailed, TargetStatusClosed:
+		return termcolor.ColorError(status)
+	case TargetStatusOpen:
+		return termcolor.ColorWarning(status)
+	case TargetStatusMerged:
+		return termcolor.ColorInfo(status)
+	default:
+		return status
+	}
+}
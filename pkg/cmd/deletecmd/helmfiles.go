@@ -0,0 +1,80 @@
+package deletecmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/helmfile/helmfile/pkg/state"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/helmfiles"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/stringhelpers"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/yaml2s"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+)
+
+// removeCharts removes the chart of the app from the helmfiles of all namespaces, or just the RemoveNamespace if it is
+// specified, using the jx-gitops helmfile editor returning the namespaces it was removed from
+func (o *Options) removeCharts(dir string, app App) ([]string, error) {
+	hfs, err := helmfiles.GatherHelmfiles("helmfile.yaml", dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to gather target helmfiles from %s: %w", dir, err)
+	}
+	namespaces, err := o.chartNamespaces(dir, hfs, app)
+	if err != nil {
+		return nil, err
+	}
+	if len(namespaces) == 0 {
+		return namespaces, nil
+	}
+	editor, err := helmfiles.NewEditor(dir, hfs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create helmfile editor: %w", err)
+	}
+	err = editor.DeleteChart(&helmfiles.ChartDetails{Chart: app.Repository, Namespace: o.RemoveNamespace})
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete chart %s: %w", app.Repository, err)
+	}
+	err = editor.Save()
+	if err != nil {
+		return nil, fmt.Errorf("failed to save modified helmfiles: %w", err)
+	}
+	return namespaces, nil
+}
+
+// chartNamespaces returns the sorted namespaces whose helmfiles contain the chart of the app which the helmfile editor
+// removes it from. Helmfiles without a namespace are left alone by the editor so their releases are logged as skipped
+func (o *Options) chartNamespaces(dir string, hfs []helmfiles.Helmfile, app App) ([]string, error) {
+	answer := []string{}
+	for i := range hfs {
+		path := hfs[i].Filepath
+		helmState := &state.HelmState{}
+		err := yaml2s.LoadFile(path, helmState)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load helmfile %s: %w", path, err)
+		}
+		ns := helmState.OverrideNamespace
+		if o.RemoveNamespace != "" && ns != o.RemoveNamespace {
+			continue
+		}
+		for j := range helmState.Releases {
+			release := &helmState.Releases[j]
+			if !helmfiles.MatchesChartName(release.Chart, app.Repository) {
+				continue
+			}
+			if ns == "" {
+				rel, err := filepath.Rel(dir, path)
+				if err != nil {
+					rel = path
+				}
+				log.Logger().Warnf("skipping release %s of chart %s in %s as the helmfile has no namespace", termcolor.ColorInfo(release.Name), termcolor.ColorInfo(release.Chart), termcolor.ColorInfo(rel))
+				continue
+			}
+			if stringhelpers.StringArrayIndex(answer, ns) < 0 {
+				answer = append(answer, ns)
+			}
+		}
+	}
+	sort.Strings(answer)
+	return answer, nil
+}
@@ -0,0 +1,124 @@
+package deletecmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/jenkins-x-plugins/jx-application/pkg/applications"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/input/inputfactory"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/stringhelpers"
+	"golang.org/x/term"
+)
+
+// interactive returns true if the user can be prompted which requires a terminal unless an input is configured
+func (o *Options) interactive() bool {
+	if o.BaseOptions.BatchMode {
+		return false
+	}
+	if o.Input != nil {
+		return true
+	}
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// pickApps lets the user pick the apps of the --owner, or every owner if not specified, to remove from the applications
+// in the cluster, showing the environments each one is deployed in. Environments which cannot be fetched are skipped
+// rather than failing
+func (o *Options) pickApps() error {
+	if o.RemoteCache == nil && !o.NoCache && !o.SkipRemote {
+		dir, err := applications.DefaultRemoteCacheDir()
+		if err != nil {
+			return err
+		}
+		o.RemoteCache = &applications.RemoteCache{Dir: dir}
+	}
+	list, err := applications.GetApplicationsWithOptions(o.GetContext(), &applications.GetOptions{
+		JXClient:    o.JXClient,
+		KubeClient:  o.KubeClient,
+		GitClient:   o.Git(),
+		Namespace:   o.Namespace,
+		SkipRemote:  o.SkipRemote,
+		RemoteCache: o.RemoteCache,
+	})
+	if err != nil {
+		return fmt.Errorf("fetching applications: %w", err)
+	}
+
+	// the applications of a monorepo share a git repository so lets combine their environments
+	deployed := map[App][]string{}
+	envs := list.OrderedEnvironments()
+	for i := range list.Items {
+		a := &list.Items[i]
+		app := App{Owner: a.SourceRepository.Spec.Org, Repository: a.SourceRepository.Spec.Repo}
+		if o.Owner != "" && app.Owner != o.Owner {
+			continue
+		}
+		names := deployed[app]
+		for j := range envs {
+			name := envs[j].Name
+			env, ok := a.Environments[name]
+			if ok && len(env.Deployments) > 0 && !env.IsPreview() && stringhelpers.StringArrayIndex(names, name) < 0 {
+				names = append(names, name)
+			}
+		}
+		deployed[app] = names
+	}
+	if len(deployed) == 0 {
+		if o.Owner != "" {
+			return fmt.Errorf("no applications of owner %s found in namespace %s", o.Owner, o.Namespace)
+		}
+		return fmt.Errorf("no applications found in namespace %s", o.Namespace)
+	}
+
+	choices := map[string]App{}
+	for app, names := range deployed {
+		text := app.String() + " (not deployed)"
+		if len(names) > 0 {
+			text = app.String() + " (" + strings.Join(names, ", ") + ")"
+		}
+		choices[text] = app
+	}
+	texts := make([]string, 0, len(choices))
+	for text := range choices {
+		texts = append(texts, text)
+	}
+	sort.Strings(texts)
+
+	if o.Input == nil {
+		o.Input = inputfactory.NewInput(&o.BaseOptions)
+	}
+	picked, err := o.Input.SelectNames(texts, "Pick the apps to remove:", false, "type to search the apps then use space to select them")
+	if err != nil {
+		return fmt.Errorf("failed to pick the apps to remove: %w", err)
+	}
+	for _, text := range picked {
+		app, ok := choices[text]
+		if !ok {
+			return fmt.Errorf("unknown app %s", text)
+		}
+		o.Apps = append(o.Apps, app)
+	}
+	if len(o.Apps) == 0 {
+		return fmt.Errorf("no apps were picked")
+	}
+	return nil
+}
+
+// confirmApps requires the user to type the name of each app before it is removed
+func (o *Options) confirmApps() error {
+	if o.Input == nil {
+		o.Input = inputfactory.NewInput(&o.BaseOptions)
+	}
+	for _, app := range o.Apps {
+		value, err := o.Input.PickValue(fmt.Sprintf("Type %s to confirm removing it:", app.Repository), "", true, "use --yes to skip this confirmation")
+		if err != nil {
+			return fmt.Errorf("failed to confirm removing app %s: %w", app.String(), err)
+		}
+		if strings.TrimSpace(value) != app.Repository {
+			return fmt.Errorf("typed %q rather than %s so the app was not removed", value, app.Repository)
+		}
+	}
+	return nil
+}
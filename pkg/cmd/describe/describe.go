@@ -0,0 +1,309 @@
+package describe
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-application/pkg/applications"
+	jxc "github.com/jenkins-x/jx-api/v4/pkg/client/clientset/versioned"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/helper"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/templates"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/cli"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/kube"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/kube/jxclient"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/kube/jxenv"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/options"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// OutputJSON renders the application description as JSON
+	OutputJSON = "json"
+
+	// OutputYAML renders the application description as YAML
+	OutputYAML = "yaml"
+)
+
+// OutputFormats the supported values of the --output flag
+var OutputFormats = []string{OutputJSON, OutputYAML}
+
+// Options the options for describing an application
+type Options struct {
+	options.BaseOptions
+
+	KubeClient    kubernetes.Interface
+	DynamicClient dynamic.Interface
+	JXClient      jxc.Interface
+
+	Name             string
+	CurrentNamespace string
+	Environment      string
+	Output           string
+	Events           int
+	SkipRemote       bool
+	NoCache          bool
+	CacheTTL         time.Duration
+	MatchStrategy    string
+	Timeout          time.Duration
+	RemoteCache      *applications.RemoteCache
+	GitClient        gitclient.Interface
+	CommandRunner    cmdrunner.CommandRunner
+}
+
+var (
+	cmdLong = templates.LongDesc(`
+		Describes a single application in the spirit of kubectl describe.
+
+		Shows the SourceRepository of the application and, for every environment it is deployed to, its workloads
+		with their versions, images, pod readiness, restart counts, URLs, ingress hosts and recent events. Remote
+		environments only show the versions from their release report.
+`)
+
+	cmdExample = templates.Examples(`
+		# describe the application in every environment
+		jx application describe myapp
+
+		# describe the application in the staging environment only
+		jx application describe myapp -e staging
+
+		# describe the application as YAML
+		jx application describe myapp -o yaml
+	`)
+)
+
+// NewCmdDescribe creates the command for describing an application
+func NewCmdDescribe() (*cobra.Command, *Options) {
+	o := &Options{}
+	cmd := &cobra.Command{
+		Use:     "describe <application>",
+		Short:   "Describes an application across environments",
+		Long:    cmdLong,
+		Example: cmdExample,
+		Args:    cobra.ExactArgs(1),
+		Run: func(_ *cobra.Command, args []string) {
+			o.Name = args[0]
+			err := o.Run()
+			helper.CheckErr(err)
+		},
+	}
+	cmd.Flags().StringVarP(&o.Environment, "env", "e", "", "Only describe the application in the given environment")
+	cmd.Flags().StringVarP(&o.Output, "output", "o", "", "The output format. One of: "+strings.Join(OutputFormats, "|"))
+	cmd.Flags().IntVarP(&o.Events, "events", "", applications.DefaultDescribeEvents, "The maximum number of recent events shown for each workload. Zero hides the events")
+	cmd.Flags().BoolVarP(&o.SkipRemote, "skip-remote", "", false, "Do not fetch the git repositories of remote environments")
+	cmd.Flags().BoolVarP(&o.NoCache, "no-cache", "", false, "Clone the git repositories of remote environments into temporary directories rather than reusing cached clones")
+	cmd.Flags().DurationVarP(&o.CacheTTL, "cache-ttl", "", 0, "Reuse cached clones of remote environment git repositories fetched within this duration without fetching them again")
+	cmd.Flags().StringVarP(&o.MatchStrategy, "match-strategy", "", string(applications.MatchStrategyAuto), "How workloads are matched to applications. One of: "+strings.Join(applications.MatchStrategies, "|"))
+	cmd.Flags().DurationVarP(&o.Timeout, "timeout", "", 0, "The maximum time to spend fetching the application. Zero means no timeout")
+
+	o.BaseOptions.AddBaseFlags(cmd)
+	return cmd, o
+}
+
+// Validate verifies settings
+func (o *Options) Validate() error {
+	if o.Output != "" && o.Output != OutputJSON && o.Output != OutputYAML {
+		return options.InvalidOption("output", o.Output, OutputFormats)
+	}
+	if _, err := applications.ParseMatchStrategy(o.MatchStrategy); err != nil {
+		return options.InvalidOption("match-strategy", o.MatchStrategy, applications.MatchStrategies)
+	}
+	var err error
+	if o.JXClient == nil {
+		o.JXClient, o.CurrentNamespace, err = jxclient.LazyCreateJXClientAndNamespace(o.JXClient, o.CurrentNamespace)
+		if err != nil {
+			return fmt.Errorf("failed to create jx client: %w", err)
+		}
+	}
+	if o.KubeClient == nil {
+		o.KubeClient, err = kube.LazyCreateKubeClient(o.KubeClient)
+		if err != nil {
+			return fmt.Errorf("failed to create kube client: %w", err)
+		}
+		o.DynamicClient = applications.LazyCreateDynamicClient(o.DynamicClient)
+	}
+	ns, _, err := jxenv.GetDevNamespace(o.KubeClient, o.CurrentNamespace)
+	if err != nil {
+		return fmt.Errorf("failed to find dev namespace: %w", err)
+	}
+	if ns != "" {
+		o.CurrentNamespace = ns
+	}
+	if o.RemoteCache == nil && !o.NoCache && !o.SkipRemote {
+		dir, err := applications.DefaultRemoteCacheDir()
+		if err != nil {
+			return err
+		}
+		o.RemoteCache = &applications.RemoteCache{
+			Dir: dir,
+			TTL: o.CacheTTL,
+		}
+	}
+	if o.GitClient == nil && o.CommandRunner != nil {
+		o.GitClient = cli.NewCLIClient("", o.CommandRunner)
+	}
+	if o.Out == nil {
+		o.Out = os.Stdout
+	}
+	return nil
+}
+
+// Run implements this command
+func (o *Options) Run() error {
+	err := o.Validate()
+	if err != nil {
+		return fmt.Errorf("failed to validate: %w", err)
+	}
+	err = o.BaseOptions.Validate()
+	if err != nil {
+		return fmt.Errorf("failed to validate: %w", err)
+	}
+
+	ctx := o.GetContext()
+	getOptions := &applications.GetOptions{
+		JXClient:      o.JXClient,
+		KubeClient:    o.KubeClient,
+		DynamicClient: o.DynamicClient,
+		Namespace:     o.CurrentNamespace,
+		Timeout:       o.Timeout,
+		SkipRemote:    o.SkipRemote,
+		MatchStrategy: applications.MatchStrategy(o.MatchStrategy),
+		GitClient:     o.GitClient,
+		RemoteCache:   o.RemoteCache,
+	}
+	if o.Environment != "" {
+		getOptions.Environments = []string{o.Environment}
+	}
+	list, err := applications.GetApplicationsWithOptions(ctx, getOptions)
+	if err != nil {
+		return fmt.Errorf("fetching applications: %w", err)
+	}
+	app := list.Find(o.Name)
+	if app == nil {
+		return fmt.Errorf("no application called %s could be found in namespace %s", o.Name, o.CurrentNamespace)
+	}
+
+	var envNames []string
+	for _, env := range list.OrderedEnvironments() {
+		envNames = append(envNames, env.Name)
+	}
+	describer := &applications.DescribeOptions{
+		KubeClient:    o.KubeClient,
+		DynamicClient: o.DynamicClient,
+		Events:        o.Events,
+	}
+	description, err := describer.Describe(ctx, app, envNames)
+	if err != nil {
+		return fmt.Errorf("failed to describe application %s: %w", o.Name, err)
+	}
+	return o.render(description)
+}
+
+func (o *Options) render(d *applications.Description) error {
+	switch o.Output {
+	case OutputJSON:
+		data, err := json.MarshalIndent(d, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal application to JSON: %w", err)
+		}
+		_, err = fmt.Fprintln(o.Out, string(data))
+		return err
+	case OutputYAML:
+		data, err := yaml.Marshal(d)
+		if err != nil {
+			return fmt.Errorf("failed to marshal application to YAML: %w", err)
+		}
+		_, err = fmt.Fprint(o.Out, string(data))
+		return err
+	default:
+		return writeDescription(o.Out, d, time.Now())
+	}
+}
+
+// writeDescription writes the description as indented text like kubectl describe
+func writeDescription(out io.Writer, d *applications.Description, now time.Time) error {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	line := func(indent int, format string, args ...interface{}) {
+		fmt.Fprintf(w, strings.Repeat("  ", indent)+format+"\n", args...)
+	}
+	r := d.Repository
+	line(0, "Name:\t%s", d.Name)
+	line(0, "Repository:\t%s", r.Name)
+	line(0, "Provider:\t%s", strings.TrimSpace(r.Kind+" "+r.Provider))
+	line(0, "Owner:\t%s", r.Owner)
+	line(0, "URL:\t%s", r.URL)
+	if len(d.Environments) == 0 {
+		line(0, "Environments:\t<none>")
+	}
+	for i := range d.Environments {
+		env := &d.Environments[i]
+		line(0, "")
+		line(0, "Environment:\t%s", env.Name)
+		line(1, "Namespace:\t%s", env.Namespace)
+		if env.Remote {
+			line(1, "Remote:\ttrue")
+		}
+		if env.FetchStatus != "" {
+			line(1, "Status:\t%s: %s", env.FetchStatus, env.FetchError)
+		}
+		for j := range env.Workloads {
+			wl := &env.Workloads[j]
+			name := wl.Workload
+			if name == "" {
+				name = wl.Name
+			}
+			if wl.Kind != "" {
+				name = wl.Kind + "/" + name
+			}
+			line(1, "Workload:\t%s", name)
+			version := wl.Version
+			if wl.VersionSource != "" {
+				version += " (" + wl.VersionSource + ")"
+			}
+			line(2, "Version:\t%s", version)
+			optionalLine := func(label, value string) {
+				if value != "" {
+					line(2, "%s:\t%s", label, value)
+				}
+			}
+			optionalLine("Image", wl.Image)
+			if wl.Deployment.Pods != "" || len(wl.PodDetails) > 0 {
+				line(2, "Pods:\t%s", wl.Deployment.Pods)
+				if len(wl.PodDetails) > 0 {
+					line(3, "NAME\tREADY\tSTATUS\tRESTARTS")
+					for _, p := range wl.PodDetails {
+						line(3, "%s\t%s\t%s\t%d", p.Name, p.Ready, p.Phase, p.Restarts)
+					}
+				}
+			}
+			line(2, "Restarts:\t%d", wl.Restarts)
+			optionalLine("URL", wl.URL)
+			optionalLine("Ingress Hosts", strings.Join(wl.IngressHosts, ", "))
+			if h := wl.Helm; h != nil {
+				line(2, "Helm Release:\t%s revision %d %s (chart %s-%s app version %s)", h.Name, h.Revision, h.Status, h.Chart, h.ChartVersion, h.AppVersion)
+			}
+			if len(wl.Events) > 0 {
+				line(2, "Events:")
+				line(3, "TYPE\tREASON\tAGE\tOBJECT\tMESSAGE")
+				for _, e := range wl.Events {
+					age := "<unknown>"
+					if !e.LastSeen.IsZero() {
+						age = duration.HumanDuration(now.Sub(e.LastSeen.Time))
+					}
+					line(3, "%s\t%s\t%s\t%s\t%s", e.Type, e.Reason, age, e.Object, e.Message)
+				}
+			}
+		}
+	}
+	return w.Flush()
+}
@@ -0,0 +1,228 @@
+package describe
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-application/pkg/applications"
+	v1 "github.com/jenkins-x/jx-api/v4/pkg/apis/jenkins.io/v1"
+	fakejx "github.com/jenkins-x/jx-api/v4/pkg/client/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	nv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakedyn "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/yaml"
+)
+
+func newTestOptions() *Options {
+	ns := "jx"
+	stagingNS := "jx-staging"
+	jxClient := fakejx.NewSimpleClientset(
+		&v1.SourceRepository{
+			ObjectMeta: metav1.ObjectMeta{Name: "myorg-myapp", Namespace: ns},
+			Spec: v1.SourceRepositorySpec{
+				Org:          "myorg",
+				Repo:         "myapp",
+				Provider:     "https://github.com",
+				ProviderKind: "github",
+				URL:          "https://github.com/myorg/myapp.git",
+			},
+		},
+		&v1.Environment{
+			ObjectMeta: metav1.ObjectMeta{Name: "dev", Namespace: ns},
+			Spec:       v1.EnvironmentSpec{Namespace: ns, Kind: v1.EnvironmentKindTypeDevelopment},
+		},
+		&v1.Environment{
+			ObjectMeta: metav1.ObjectMeta{Name: "staging", Namespace: ns},
+			Spec:       v1.EnvironmentSpec{Namespace: stagingNS, Kind: v1.EnvironmentKindTypePermanent},
+		},
+	)
+
+	labels := map[string]string{"app": "myapp"}
+	lastSeen := metav1.NewTime(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC))
+	kubeClient := fake.NewSimpleClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: ns}},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "myapp", Namespace: stagingNS, Labels: map[string]string{"version": "1.0.0"}},
+			Spec: appsv1.DeploymentSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: labels},
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "myapp", Image: "ghcr.io/myorg/myapp:1.0.0"}}},
+				},
+			},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "myapp-abc-123", Namespace: stagingNS, Labels: labels},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "myapp", Image: "ghcr.io/myorg/myapp:1.0.0"}}},
+			Status: corev1.PodStatus{
+				Phase:             corev1.PodRunning,
+				ContainerStatuses: []corev1.ContainerStatus{{Name: "myapp", Ready: true, RestartCount: 2}},
+			},
+		},
+		&nv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Name: "myapp", Namespace: stagingNS},
+			Spec:       nv1.IngressSpec{Rules: []nv1.IngressRule{{Host: "myapp-jx-staging.example.com"}}},
+		},
+		&corev1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: "myapp-abc-123.1", Namespace: stagingNS},
+			InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "myapp-abc-123"},
+			Type:           corev1.EventTypeWarning,
+			Reason:         "BackOff",
+			Message:        "Back-off restarting failed container",
+			Count:          2,
+			LastTimestamp:  lastSeen,
+		},
+		&corev1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: "other.1", Namespace: stagingNS},
+			InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "other-abc-123"},
+			Reason:         "Pulled",
+		},
+	)
+	dynamicClient := fakedyn.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		applications.KnativeServiceResource:   "ServiceList",
+		applications.InferenceServiceResource: "InferenceServiceList",
+		applications.RolloutResource:          "RolloutList",
+		applications.CanaryResource:           "CanaryList",
+	})
+
+	_, o := NewCmdDescribe()
+	o.Name = "myapp"
+	o.JXClient = jxClient
+	o.KubeClient = kubeClient
+	o.DynamicClient = dynamicClient
+	o.CurrentNamespace = ns
+	o.Ctx = context.TODO()
+	return o
+}
+
+func TestDescribe(t *testing.T) {
+	o := newTestOptions()
+	o.Output = OutputYAML
+	out := &bytes.Buffer{}
+	o.Out = out
+	err := o.Run()
+	require.NoError(t, err)
+
+	d := &applications.Description{}
+	require.NoError(t, yaml.Unmarshal(out.Bytes(), d))
+	assert.Equal(t, applications.OutputDescriptionKind, d.Kind)
+	assert.Equal(t, "myapp", d.Name)
+	assert.Equal(t, applications.DescribeRepository{
+		Name:     "myorg-myapp",
+		Provider: "https://github.com",
+		Kind:     "github",
+		Owner:    "myorg",
+		Repo:     "myapp",
+		URL:      "https://github.com/myorg/myapp.git",
+	}, d.Repository)
+	require.Len(t, d.Environments, 1)
+	env := d.Environments[0]
+	assert.Equal(t, "staging", env.Name)
+	require.Len(t, env.Workloads, 1)
+	w := env.Workloads[0]
+	assert.Equal(t, "myapp", w.Workload)
+	assert.Equal(t, "1.0.0", w.Version)
+	assert.Equal(t, "ghcr.io/myorg/myapp:1.0.0", w.Image)
+	assert.Equal(t, int32(2), w.Restarts)
+	assert.Equal(t, []applications.DescribePod{{Name: "myapp-abc-123", Phase: "Running", Ready: "1/1", Restarts: 2, Images: []string{"ghcr.io/myorg/myapp:1.0.0"}}}, w.PodDetails)
+	assert.Equal(t, []string{"myapp-jx-staging.example.com"}, w.IngressHosts)
+	require.Len(t, w.Events, 1, "only the events of the workload and its pods should be shown")
+	assert.Equal(t, "BackOff", w.Events[0].Reason)
+	assert.Equal(t, "pod/myapp-abc-123", w.Events[0].Object)
+}
+
+func TestDescribeText(t *testing.T) {
+	o := newTestOptions()
+	out := &bytes.Buffer{}
+	o.Out = out
+	err := o.Run()
+	require.NoError(t, err)
+
+	lines := strings.Split(out.String(), "\n")
+	var fields []string
+	for _, line := range lines {
+		fields = append(fields, strings.Join(strings.Fields(line), " "))
+	}
+	assert.Contains(t, fields, "Name: myapp")
+	assert.Contains(t, fields, "Environment: staging")
+	assert.Contains(t, fields, "Workload: Deployment/myapp")
+	assert.Contains(t, fields, "Version: 1.0.0 (label:version)")
+	assert.Contains(t, fields, "Ingress Hosts: myapp-jx-staging.example.com")
+	assert.Contains(t, fields, "myapp-abc-123 1/1 Running 2")
+	pods := 0
+	for _, f := range fields {
+		if strings.HasPrefix(f, "Pods:") {
+			pods++
+		}
+	}
+	assert.Equal(t, 1, pods, "should show the pods of the workload once")
+	assert.Contains(t, out.String(), "Back-off restarting failed container")
+
+	o = newTestOptions()
+	o.Name = "unknown"
+	o.Out = out
+	assert.Error(t, o.Run())
+}
+
+func TestDescribeListsNamespaceOnce(t *testing.T) {
+	o := newTestOptions()
+	kubeClient := o.KubeClient.(*fake.Clientset)
+	_, err := kubeClient.AppsV1().StatefulSets("jx-staging").Create(context.TODO(), &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "myapp", Namespace: "jx-staging", Labels: map[string]string{"version": "1.0.0"}},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+	o.Output = OutputJSON
+	out := &bytes.Buffer{}
+	o.Out = out
+	kubeClient.ClearActions()
+	require.NoError(t, o.Run())
+
+	d := &applications.Description{}
+	require.NoError(t, yaml.Unmarshal(out.Bytes(), d))
+	require.Len(t, d.Environments, 1)
+	require.Len(t, d.Environments[0].Workloads, 2)
+	for _, w := range d.Environments[0].Workloads {
+		assert.Equal(t, []string{"myapp-jx-staging.example.com"}, w.IngressHosts, "ingress hosts of %s", w.Kind)
+	}
+	assert.Contains(t, out.String(), `"name": "myapp"`, "the deployment fields should be inlined")
+
+	lists := map[string]int{}
+	for _, action := range kubeClient.Actions() {
+		if action.GetVerb() == "list" && action.GetNamespace() == "jx-staging" {
+			lists[action.GetResource().Resource]++
+		}
+	}
+	assert.Equal(t, 1, lists["ingresses"], "should list the ingresses once per namespace")
+	assert.Equal(t, 1, lists["events"], "should list the events once per namespace")
+}
+
+func TestDescribeArgs(t *testing.T) {
+	cmd, _ := NewCmdDescribe()
+	assert.Error(t, cmd.Args(cmd, nil), "should require the application name")
+	assert.Error(t, cmd.Args(cmd, []string{"myapp", "other"}), "should only accept one application")
+	assert.NoError(t, cmd.Args(cmd, []string{"myapp"}))
+}
+
+func TestDescribeRemoteCache(t *testing.T) {
+	t.Setenv("JX3_HOME", t.TempDir())
+
+	o := newTestOptions()
+	o.CacheTTL = time.Minute
+	require.NoError(t, o.Validate())
+	require.NotNil(t, o.RemoteCache, "should cache the clones of remote environments")
+	assert.Equal(t, time.Minute, o.RemoteCache.TTL)
+
+	o = newTestOptions()
+	o.NoCache = true
+	require.NoError(t, o.Validate())
+	assert.Nil(t, o.RemoteCache)
+}
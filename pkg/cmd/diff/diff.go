@@ -0,0 +1,252 @@
+package diff
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-application/pkg/applications"
+	jxc "github.com/jenkins-x/jx-api/v4/pkg/client/clientset/versioned"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/helper"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/templates"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/kube"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/kube/jxclient"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/kube/jxenv"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/options"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/table"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// OutputJSON renders the comparison as JSON
+	OutputJSON = "json"
+
+	// OutputYAML renders the comparison as YAML
+	OutputYAML = "yaml"
+
+	// DriftExitCode the exit code used when the environments have drifted. Other failures exit with 1
+	DriftExitCode = 2
+)
+
+// OutputFormats the supported values of the --output flag
+var OutputFormats = []string{OutputJSON, OutputYAML}
+
+// ErrDrift is returned by Run when any application differs between the environments
+var ErrDrift = errors.New("the environments have drifted")
+
+// Options the options for comparing the applications in two environments
+type Options struct {
+	options.BaseOptions
+
+	KubeClient    kubernetes.Interface
+	DynamicClient dynamic.Interface
+	JXClient      jxc.Interface
+
+	CurrentNamespace string
+	From             string
+	To               string
+	Output           string
+	OnlyDrift        bool
+	NoExitCode       bool
+	SkipRemote       bool
+	MatchStrategy    string
+	Timeout          time.Duration
+}
+
+var (
+	cmdLong = templates.LongDesc(`
+		Compares the version of every application in two environments.
+
+		Each application deployed in either environment is reported as:
+
+		* identical if the same version is deployed in both environments
+		* ahead if the version in the --from environment is newer than in the --to environment, e.g. awaiting promotion
+		* behind if the version in the --from environment is older than in the --to environment
+		* missing-in-<environment> if it is only deployed in the other environment, e.g. missing-in-production
+
+		Versions are compared using semantic versioning where possible. The command exits with code 2 if any
+		application is not identical so that it can gate pipelines, unless --no-exit-code is specified.
+`)
+
+	cmdExample = templates.Examples(`
+		# compare the applications in staging and production
+		jx application diff --from staging --to production
+
+		# only show the applications which differ as YAML
+		jx application diff --from staging --to production --only-drift -o yaml
+	`)
+)
+
+// NewCmdDiff creates the command for comparing the applications in two environments
+func NewCmdDiff() (*cobra.Command, *Options) {
+	o := &Options{}
+	cmd := &cobra.Command{
+		Use:     "diff",
+		Short:   "Compares the versions of the applications in two environments",
+		Long:    cmdLong,
+		Example: cmdExample,
+		Run: func(_ *cobra.Command, _ []string) {
+			err := o.Run()
+			if errors.Is(err, ErrDrift) {
+				os.Exit(DriftExitCode)
+			}
+			helper.CheckErr(err)
+		},
+	}
+	cmd.Flags().StringVarP(&o.From, "from", "f", "", "The environment to compare from, e.g. staging")
+	cmd.Flags().StringVarP(&o.To, "to", "t", "", "The environment to compare to, e.g. production")
+	cmd.Flags().StringVarP(&o.Output, "output", "o", "", "The output format. One of: "+strings.Join(OutputFormats, "|"))
+	cmd.Flags().BoolVarP(&o.OnlyDrift, "only-drift", "", false, "Only show the applications which are not identical in both environments")
+	cmd.Flags().BoolVarP(&o.NoExitCode, "no-exit-code", "", false, "Exit with 0 even if the environments have drifted")
+	cmd.Flags().BoolVarP(&o.SkipRemote, "skip-remote", "", false, "Do not fetch the git repositories of remote environments")
+	cmd.Flags().StringVarP(&o.MatchStrategy, "match-strategy", "", string(applications.MatchStrategyAuto), "How workloads are matched to applications. One of: "+strings.Join(applications.MatchStrategies, "|"))
+	cmd.Flags().DurationVarP(&o.Timeout, "timeout", "", 0, "The maximum time to spend fetching the applications. Zero means no timeout")
+
+	o.BaseOptions.AddBaseFlags(cmd)
+	return cmd, o
+}
+
+// Validate verifies settings
+func (o *Options) Validate() error {
+	if o.From == "" {
+		return options.MissingOption("from")
+	}
+	if o.To == "" {
+		return options.MissingOption("to")
+	}
+	if o.Output != "" && o.Output != OutputJSON && o.Output != OutputYAML {
+		return options.InvalidOption("output", o.Output, OutputFormats)
+	}
+	if _, err := applications.ParseMatchStrategy(o.MatchStrategy); err != nil {
+		return options.InvalidOption("match-strategy", o.MatchStrategy, applications.MatchStrategies)
+	}
+	var err error
+	if o.JXClient == nil {
+		o.JXClient, o.CurrentNamespace, err = jxclient.LazyCreateJXClientAndNamespace(o.JXClient, o.CurrentNamespace)
+		if err != nil {
+			return fmt.Errorf("failed to create jx client: %w", err)
+		}
+	}
+	if o.KubeClient == nil {
+		o.KubeClient, err = kube.LazyCreateKubeClient(o.KubeClient)
+		if err != nil {
+			return fmt.Errorf("failed to create kube client: %w", err)
+		}
+		o.DynamicClient = applications.LazyCreateDynamicClient(o.DynamicClient)
+	}
+	ns, _, err := jxenv.GetDevNamespace(o.KubeClient, o.CurrentNamespace)
+	if err != nil {
+		return fmt.Errorf("failed to find dev namespace: %w", err)
+	}
+	if ns != "" {
+		o.CurrentNamespace = ns
+	}
+	if o.Out == nil {
+		o.Out = os.Stdout
+	}
+	return nil
+}
+
+// Run implements this command returning ErrDrift if the environments have drifted
+func (o *Options) Run() error {
+	err := o.Validate()
+	if err != nil {
+		return fmt.Errorf("failed to validate: %w", err)
+	}
+	err = o.BaseOptions.Validate()
+	if err != nil {
+		return fmt.Errorf("failed to validate: %w", err)
+	}
+
+	ctx := o.GetContext()
+	for _, name := range []string{o.From, o.To} {
+		_, err = o.JXClient.JenkinsV1().Environments(o.CurrentNamespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to find environment %s in namespace %s: %w", name, o.CurrentNamespace, err)
+		}
+	}
+
+	// an environment which cannot be fetched would otherwise show its applications as missing
+	list, err := applications.GetApplicationsWithOptions(ctx, &applications.GetOptions{
+		JXClient:      o.JXClient,
+		KubeClient:    o.KubeClient,
+		DynamicClient: o.DynamicClient,
+		Namespace:     o.CurrentNamespace,
+		Environments:  []string{o.From, o.To},
+		Timeout:       o.Timeout,
+		SkipRemote:    o.SkipRemote,
+		MatchStrategy: applications.MatchStrategy(o.MatchStrategy),
+		Strict:        true,
+	})
+	if err != nil {
+		return fmt.Errorf("fetching applications: %w", err)
+	}
+
+	d := list.Diff(o.From, o.To)
+	drift := d.HasDrift()
+	if o.OnlyDrift {
+		items := []applications.DiffItem{}
+		for _, item := range d.Items {
+			if item.Status != applications.DiffStatusIdentical {
+				items = append(items, item)
+			}
+		}
+		d.Items = items
+	}
+	err = o.render(&d)
+	if err != nil {
+		return err
+	}
+	if drift && !o.NoExitCode {
+		return ErrDrift
+	}
+	return nil
+}
+
+func (o *Options) render(d *applications.Diff) error {
+	switch o.Output {
+	case OutputJSON:
+		data, err := json.MarshalIndent(d, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal diff to JSON: %w", err)
+		}
+		_, err = fmt.Fprintln(o.Out, string(data))
+		return err
+	case OutputYAML:
+		data, err := yaml.Marshal(d)
+		if err != nil {
+			return fmt.Errorf("failed to marshal diff to YAML: %w", err)
+		}
+		_, err = fmt.Fprint(o.Out, string(data))
+		return err
+	default:
+		t := table.CreateTable(o.Out)
+		t.AddRow("APPLICATION", strings.ToUpper(d.From), strings.ToUpper(d.To), "STATUS")
+		for _, item := range d.Items {
+			t.AddRow(item.Name, item.FromVersion, item.ToVersion, statusCell(item.Status))
+		}
+		t.Render()
+		return nil
+	}
+}
+
+// statusCell highlights the applications which are not identical
+func statusCell(status applications.DiffStatus) string {
+	text := string(status)
+	switch {
+	case status.IsMissing():
+		return termcolor.ColorError(text)
+	case status == applications.DiffStatusAhead || status == applications.DiffStatusBehind:
+		return termcolor.ColorWarning(text)
+	default:
+		return text
+	}
+}
@@ -0,0 +1,108 @@
+package diff
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jenkins-x-plugins/jx-application/pkg/applications"
+	v1 "github.com/jenkins-x/jx-api/v4/pkg/apis/jenkins.io/v1"
+	fakejx "github.com/jenkins-x/jx-api/v4/pkg/client/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakedyn "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/yaml"
+)
+
+func newTestOptions(productionVersion string) *Options {
+	ns := "jx"
+	jxClient := fakejx.NewSimpleClientset(
+		&v1.SourceRepository{
+			ObjectMeta: metav1.ObjectMeta{Name: "myorg-myapp", Namespace: ns},
+			Spec:       v1.SourceRepositorySpec{Org: "myorg", Repo: "myapp"},
+		},
+		&v1.Environment{
+			ObjectMeta: metav1.ObjectMeta{Name: "staging", Namespace: ns},
+			Spec:       v1.EnvironmentSpec{Namespace: "jx-staging", Kind: v1.EnvironmentKindTypePermanent},
+		},
+		&v1.Environment{
+			ObjectMeta: metav1.ObjectMeta{Name: "production", Namespace: ns},
+			Spec:       v1.EnvironmentSpec{Namespace: "jx-production", Kind: v1.EnvironmentKindTypePermanent},
+		},
+	)
+	deployment := func(ns, version string) *appsv1.Deployment {
+		return &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "myapp", Namespace: ns, Labels: map[string]string{"version": version}}}
+	}
+	kubeClient := fake.NewSimpleClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: ns}},
+		deployment("jx-staging", "1.10.0"),
+		deployment("jx-production", productionVersion),
+	)
+	dynamicClient := fakedyn.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		applications.KnativeServiceResource:   "ServiceList",
+		applications.InferenceServiceResource: "InferenceServiceList",
+		applications.RolloutResource:          "RolloutList",
+		applications.CanaryResource:           "CanaryList",
+	})
+
+	_, o := NewCmdDiff()
+	o.From = "staging"
+	o.To = "production"
+	o.JXClient = jxClient
+	o.KubeClient = kubeClient
+	o.DynamicClient = dynamicClient
+	o.CurrentNamespace = ns
+	o.Ctx = context.TODO()
+	return o
+}
+
+func TestDiff(t *testing.T) {
+	o := newTestOptions("1.9.0")
+	out := &bytes.Buffer{}
+	o.Out = out
+	err := o.Run()
+	assert.ErrorIs(t, err, ErrDrift)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Equal(t, []string{"APPLICATION", "STAGING", "PRODUCTION", "STATUS"}, strings.Fields(lines[0]))
+	assert.Equal(t, []string{"myapp", "1.10.0", "1.9.0"}, strings.Fields(lines[1])[:3])
+	assert.Contains(t, lines[1], string(applications.DiffStatusAhead))
+
+	o = newTestOptions("1.9.0")
+	o.Out = out
+	o.NoExitCode = true
+	assert.NoError(t, o.Run())
+
+	o = newTestOptions("1.10.0")
+	out.Reset()
+	o.Out = out
+	o.Output = OutputYAML
+	o.OnlyDrift = true
+	require.NoError(t, o.Run(), "identical environments should not fail")
+	d := &applications.Diff{}
+	require.NoError(t, yaml.Unmarshal(out.Bytes(), d))
+	assert.Empty(t, d.Items)
+
+	o = newTestOptions("1.10.0")
+	require.NoError(t, o.KubeClient.AppsV1().Deployments("jx-production").Delete(context.TODO(), "myapp", metav1.DeleteOptions{}))
+	out.Reset()
+	o.Out = out
+	o.OnlyDrift = true
+	assert.ErrorIs(t, o.Run(), ErrDrift)
+	lines = strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Equal(t, []string{"myapp", "1.10.0"}, strings.Fields(lines[1])[:2])
+	assert.Contains(t, lines[1], "missing-in-production")
+
+	o = newTestOptions("1.10.0")
+	o.To = "unknown"
+	assert.Error(t, o.Run())
+}
@@ -4,22 +4,28 @@ import (
 	"fmt"
 	"os"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/cli"
 
 	"github.com/jenkins-x-plugins/jx-application/pkg/applications"
+	"github.com/jenkins-x/go-scm/scm"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/kube/jxclient"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/kube/jxenv"
 
 	"github.com/jenkins-x/jx-helpers/v3/pkg/options"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/stringhelpers"
 
 	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/helper"
 
 	"github.com/jenkins-x/jx-helpers/v3/pkg/table"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
 
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 
 	v1 "github.com/jenkins-x/jx-api/v4/pkg/apis/jenkins.io/v1"
@@ -35,16 +41,35 @@ import (
 type ApplicationsOptions struct {
 	options.BaseOptions
 
-	KubeClient kubernetes.Interface
-	JXClient   jxc.Interface
-
-	CurrentNamespace string
-	Namespace        string
-	Environment      string
-	HideURL          bool
-	HidePod          bool
-	GitClient        gitclient.Interface
-	CommandRunner    cmdrunner.CommandRunner
+	KubeClient    kubernetes.Interface
+	DynamicClient dynamic.Interface
+	JXClient      jxc.Interface
+
+	CurrentNamespace            string
+	Namespace                   string
+	Environment                 string
+	HideURL                     bool
+	HidePod                     bool
+	Output                      string
+	Watch                       bool
+	RemotePollInterval          time.Duration
+	Selector                    string
+	DeploymentSelector          string
+	Timeout                     time.Duration
+	RemoteTimeout               time.Duration
+	SkipRemote                  bool
+	Parallelism                 int
+	Strict                      bool
+	NoCache                     bool
+	CacheTTL                    time.Duration
+	RemoteCache                 *applications.RemoteCache
+	RemoteFetcher               string
+	ProgressiveDelivery         []string
+	MatchStrategy               string
+	ReleaseFetcher              applications.ReleaseFetcher
+	ProgressiveDeliveryBackends []applications.ProgressiveDeliveryBackend
+	GitClient                   gitclient.Interface
+	CommandRunner               cmdrunner.CommandRunner
 }
 
 // Applications is a map indexed by the application name then the environment name
@@ -67,6 +92,26 @@ type ApplicationEnvironmentInfo struct {
 var (
 	getVersionLong = templates.LongDesc(`
 		Display applications across environments.
+
+		A SourceRepository which produces several applications, such as a monorepo with a chart per application, can
+		list their names in the comma separated jenkins.io/applications annotation to show an application per name.
+
+		Workloads are matched to applications using the app.kubernetes.io/name label, the jenkins.io/source-repository
+		annotation naming the SourceRepository of the workload or, for workloads whose labels and annotations match
+		no application, the application name deduced from the workload name. A workload whose
+		jenkins.io/source-repository annotation names another SourceRepository is never matched by its name. The
+		meta.helm.sh/release-name annotation and app.kubernetes.io/part-of label are only used for applications with
+		no workloads matching by name, so that subcharts such as databases are not shown as part of the application.
+		Each workload is shown for at most one application. Use --match-strategy=metadata to only use the labels and
+		annotations or --match-strategy=name to only use the workload name.
+
+		The version of a workload is found from its version, app.kubernetes.io/version, chart or helm.sh/chart labels,
+		its deployed helm release or the image tag of its primary container. The wide output shows the image version
+		of any workload whose labels disagree with the image it is running.
+
+		The Helm 3 release secrets in each environment are decoded to report the chart, app version, revision, status
+		and last deploy time of each application in the yaml and json output. Failed or pending releases are
+		highlighted next to the version, including releases which failed before creating any workloads.
 `)
 
 	getVersionExample = templates.Examples(`
@@ -82,6 +127,28 @@ var (
 		jx get applications -u
 		# List applications just showing the versions (hiding urls and pod counts)
 		jx get applications -u -p
+		# List applications for SourceRepositories with the given label
+		jx get applications -l owner=myorg
+		# List applications without fetching the git repositories of remote environments
+		jx get applications --skip-remote
+		# List applications only matching workloads by their labels and annotations
+		jx get applications --match-strategy metadata
+		# List applications reusing clones of remote environment git repositories fetched in the last 10 minutes
+		jx get applications --cache-ttl 10m
+		# List applications with additional columns
+		jx get applications -o wide
+		# List applications as JSON for use in scripts
+		jx get applications -o json
+		# List just the application names
+		jx get applications -o name
+		# List the production version of each application using a go template over the application list
+		jx get applications -o go-template='{{range .Items}}{{.Name}} {{appVersion . "production"}}{{"\n"}}{{end}}'
+		# Watch applications, highlighting changes as they are promoted
+		jx get applications -w
+		# Watch applications polling remote environment git repositories every 5 minutes
+		jx get applications -w --remote-poll-interval 5m
+		# List the repository names using a JSONPath expression over the JSON output
+		jx get applications -o jsonpath='{.items[*].repository}'
 	`)
 )
 
@@ -103,13 +170,34 @@ func NewCmdGetApplications() (*cobra.Command, *ApplicationsOptions) {
 	cmd.Flags().BoolVarP(&o.HidePod, "pod", "p", false, "Hide the pod counts")
 	cmd.Flags().StringVarP(&o.Environment, "env", "e", "", "Filter applications in the given environment")
 	cmd.Flags().StringVarP(&o.Namespace, "namespace", "n", "", "Filter applications in the given namespace")
+	cmd.Flags().StringVarP(&o.Output, "output", "o", "", "The output format. One of: "+strings.Join(OutputFormats, "|"))
+	cmd.Flags().StringVarP(&o.Selector, "selector", "l", "", "The label selector used to filter the SourceRepositories of applications")
+	cmd.Flags().StringVarP(&o.DeploymentSelector, "deployment-selector", "", "", "The label selector used to filter the Deployments in each environment")
+	cmd.Flags().DurationVarP(&o.Timeout, "timeout", "", 0, "The maximum time to spend fetching applications. Zero means no timeout. Cannot be used with --watch")
+	cmd.Flags().DurationVarP(&o.RemoteTimeout, "remote-timeout", "", 2*time.Minute, "The maximum time to spend fetching the git repository of each remote environment. Zero means no timeout")
+	cmd.Flags().BoolVarP(&o.SkipRemote, "skip-remote", "", false, "Do not fetch the git repositories of remote environments")
+	cmd.Flags().IntVarP(&o.Parallelism, "parallelism", "", applications.DefaultParallelism, "The maximum number of environments to fetch concurrently")
+	cmd.Flags().BoolVarP(&o.NoCache, "no-cache", "", false, "Clone the git repositories of remote environments into temporary directories rather than reusing cached clones")
+	cmd.Flags().DurationVarP(&o.CacheTTL, "cache-ttl", "", 0, "Reuse cached clones of remote environment git repositories fetched within this duration without fetching them again")
+	cmd.Flags().StringVarP(&o.RemoteFetcher, "remote-fetcher", "", applications.FetcherClone, "How to fetch the releases of remote environments. One of: "+strings.Join(applications.Fetchers, "|"))
+	cmd.Flags().StringSliceVarP(&o.ProgressiveDelivery, "progressive-delivery", "", applications.ProgressiveDeliveryBackendNames, "The progressive delivery backends whose rollouts are shown. Any of: "+strings.Join(applications.ProgressiveDeliveryBackendNames, "|"))
+	cmd.Flags().StringVarP(&o.MatchStrategy, "match-strategy", "", string(applications.MatchStrategyAuto), "How workloads are matched to applications. One of: "+strings.Join(applications.MatchStrategies, "|"))
+	cmd.Flags().BoolVarP(&o.Strict, "strict", "", false, "Fail if any environment cannot be fetched rather than showing the other environments")
+	cmd.Flags().BoolVarP(&o.Watch, "watch", "w", false, "Watch for changes to the applications and re-render them")
+	cmd.Flags().DurationVarP(&o.RemotePollInterval, "remote-poll-interval", "", time.Minute, "How often to poll the git repositories of remote environments when watching")
 
 	return cmd, o
 }
 
 // Validate verifies settings
 func (o *ApplicationsOptions) Validate() error {
-	var err error
+	err := o.validateOutput()
+	if err != nil {
+		return err
+	}
+	if o.Watch && o.Timeout > 0 {
+		return options.InvalidOptionf("timeout", o.Timeout, "cannot be used with --watch which runs until it is interrupted")
+	}
 	if o.JXClient == nil {
 		o.JXClient, o.CurrentNamespace, err = jxclient.LazyCreateJXClientAndNamespace(o.JXClient, o.CurrentNamespace)
 		if err != nil {
@@ -122,6 +210,7 @@ func (o *ApplicationsOptions) Validate() error {
 		if err != nil {
 			return fmt.Errorf("failed to create kube client: %w", err)
 		}
+		o.DynamicClient = applications.LazyCreateDynamicClient(o.DynamicClient)
 	}
 	ns, _, err := jxenv.GetDevNamespace(o.KubeClient, o.CurrentNamespace)
 	if err != nil {
@@ -130,12 +219,37 @@ func (o *ApplicationsOptions) Validate() error {
 	if ns != "" {
 		o.CurrentNamespace = ns
 	}
-	if o.CommandRunner == nil {
-		o.CommandRunner = cmdrunner.QuietCommandRunner
+	if o.RemoteCache == nil && !o.NoCache {
+		dir, err := applications.DefaultRemoteCacheDir()
+		if err != nil {
+			return err
+		}
+		o.RemoteCache = &applications.RemoteCache{
+			Dir: dir,
+			TTL: o.CacheTTL,
+		}
 	}
-	if o.GitClient == nil {
+	if o.GitClient == nil && o.CommandRunner != nil {
 		o.GitClient = cli.NewCLIClient("", o.CommandRunner)
 	}
+	if o.ProgressiveDeliveryBackends == nil {
+		o.ProgressiveDeliveryBackends, err = applications.NewProgressiveDeliveryBackends(o.ProgressiveDelivery)
+		if err != nil {
+			return options.InvalidOptionf("progressive-delivery", o.ProgressiveDelivery, "%s", err.Error())
+		}
+	}
+	if _, err = applications.ParseMatchStrategy(o.MatchStrategy); err != nil {
+		return options.InvalidOption("match-strategy", o.MatchStrategy, applications.MatchStrategies)
+	}
+	if o.ReleaseFetcher == nil {
+		o.ReleaseFetcher, err = applications.NewReleaseFetcher(o.RemoteFetcher, o.GitClient, o.RemoteCache)
+		if err != nil {
+			return options.InvalidOption("remote-fetcher", o.RemoteFetcher, applications.Fetchers)
+		}
+	}
+	if o.Out == nil {
+		o.Out = os.Stdout
+	}
 	return nil
 }
 
@@ -150,23 +264,61 @@ func (o *ApplicationsOptions) Run() error {
 		return fmt.Errorf("failed to validate: %w", err)
 	}
 
-	list, err := applications.GetApplications(o.JXClient, o.KubeClient, o.CurrentNamespace, o.GitClient)
+	if o.Watch {
+		return o.watch()
+	}
+
+	list, err := applications.GetApplicationsWithOptions(o.GetContext(), o.getOptions())
 	if err != nil {
 		return fmt.Errorf("fetching applications: %w", err)
 	}
-	if len(list.Items) == 0 {
-		log.Logger().Infof("No applications found")
-		return nil
+	if o.isTableOutput() {
+		o.warnEnvironmentFailures(list)
+		if len(list.Items) == 0 {
+			log.Logger().Infof("No applications found")
+			return nil
+		}
 	}
+	return o.render(list)
+}
 
-	table := o.generateTable(list)
-	table.Render()
+// warnEnvironmentFailures logs a warning for each environment which could not be fetched
+func (o *ApplicationsOptions) warnEnvironmentFailures(list applications.List) {
+	for i := range list.Failures {
+		env := &list.Failures[i]
+		log.Logger().Warnf("could not fetch environment %s: %s", env.Name, env.FetchError)
+	}
+}
 
-	return nil
+// getOptions returns the options used to fetch the applications
+func (o *ApplicationsOptions) getOptions() *applications.GetOptions {
+	answer := &applications.GetOptions{
+		JXClient:            o.JXClient,
+		KubeClient:          o.KubeClient,
+		DynamicClient:       o.DynamicClient,
+		GitClient:           o.GitClient,
+		Namespace:           o.CurrentNamespace,
+		RepositorySelector:  o.Selector,
+		DeploymentSelector:  o.DeploymentSelector,
+		Timeout:             o.Timeout,
+		RemoteTimeout:       o.RemoteTimeout,
+		SkipRemote:          o.SkipRemote,
+		Parallelism:         o.Parallelism,
+		Strict:              o.Strict,
+		RemoteCache:         o.RemoteCache,
+		ReleaseFetcher:      o.ReleaseFetcher,
+		ProgressiveDelivery: o.ProgressiveDeliveryBackends,
+		MatchStrategy:       applications.MatchStrategy(o.MatchStrategy),
+	}
+	if o.Environment != "" {
+		answer.Environments = []string{o.Environment}
+	}
+	return answer
 }
 
 func (o *ApplicationsOptions) generateTable(list applications.List) table.Table {
 	table := o.generateTableHeaders(list)
+	canary := hasRollouts(list)
 
 	for i := range list.Items {
 		a := &list.Items[i]
@@ -177,32 +329,22 @@ func (o *ApplicationsOptions) generateTable(list applications.List) table.Table
 			envMap := list.Environments()
 			keys := o.sortedKeys(envMap)
 			for _, k := range keys {
-				if ae, ok := environments[k]; ok {
-					for _, d := range ae.Deployments {
-						name = d.Name
-						if !ae.IsPreview() {
-							row = append(row, d.Version)
-						}
-						if !o.HidePod {
-							row = append(row, d.Pods)
-						}
-						if !o.HideURL {
-							row = append(row, d.URL)
-						}
-					}
-				} else {
-					if !ae.IsPreview() {
-						row = append(row, "")
-					}
-					if !o.HidePod {
-						row = append(row, "")
-					}
-					if !o.HideURL {
-						row = append(row, "")
-					}
+				ae, ok := environments[k]
+				switch {
+				case ok && ae.FetchStatus != "":
+					row = append(row, o.environmentCells(&ae, "<"+strings.ToLower(ae.FetchStatus)+">", canary)...)
+				case ok && len(ae.Deployments) > 0:
+					name = ae.Deployments[0].Name
+					row = append(row, o.deploymentCells(&ae, canary)...)
+				default:
+					row = append(row, o.environmentCells(&ae, "", canary)...)
 				}
 			}
-			row = append([]string{name}, row...)
+			prefix := []string{name}
+			if o.Output == OutputWide {
+				prefix = append(prefix, repositoryName(a), a.Spec.ProviderKind, workloadKinds(a))
+			}
+			row = append(prefix, row...)
 
 			table.AddRow(row...)
 		}
@@ -210,6 +352,164 @@ func (o *ApplicationsOptions) generateTable(list applications.List) table.Table
 	return table
 }
 
+// deploymentCells returns the cells of an environment combining every deployment of the application in it. The
+// pods are summed and the distinct versions, rollouts and URLs are listed
+func (o *ApplicationsOptions) deploymentCells(ae *applications.Environment, canary bool) []string {
+	var versions, rollouts, urls []string
+	for i := range ae.Deployments {
+		d := &ae.Deployments[i]
+		version := versionCell(d)
+		if o.Output == OutputWide && d.ImageVersionMismatch() {
+			// the labels disagree with the image actually running
+			version += " (image " + applications.ImageVersion(d.Image) + ")"
+		}
+		if status := helmStatusCell(d.Helm); status != "" {
+			version = strings.TrimSpace(version + " " + status)
+		}
+		versions = appendUnique(versions, version)
+		rollouts = appendUnique(rollouts, canaryCell(d.Rollout))
+		urls = appendUnique(urls, d.URL)
+	}
+	var cells []string
+	if !ae.IsPreview() {
+		cells = append(cells, strings.Join(versions, ","))
+	}
+	if canary {
+		cells = append(cells, strings.Join(rollouts, ","))
+	}
+	if !o.HidePod {
+		cells = append(cells, aggregatePods(ae.Deployments))
+	}
+	if !o.HideURL {
+		cells = append(cells, strings.Join(urls, ","))
+	}
+	return cells
+}
+
+// aggregatePods sums the ready/replicas pods of the deployments. Any pods which are not a ratio, such as the active
+// jobs of a CronJob, are listed after the sum
+func aggregatePods(deployments []applications.Deployment) string {
+	if len(deployments) == 1 {
+		return deployments[0].Pods
+	}
+	var ready, replicas int
+	ratios := 0
+	var others []string
+	for i := range deployments {
+		pods := deployments[i].Pods
+		if pods == "" {
+			continue
+		}
+		r, total, found := strings.Cut(pods, "/")
+		readyCount, err1 := strconv.Atoi(r)
+		replicaCount, err2 := strconv.Atoi(total)
+		if !found || err1 != nil || err2 != nil {
+			others = appendUnique(others, pods)
+			continue
+		}
+		ready += readyCount
+		replicas += replicaCount
+		ratios++
+	}
+	if ratios > 0 {
+		others = append([]string{strconv.Itoa(ready) + "/" + strconv.Itoa(replicas)}, others...)
+	}
+	return strings.Join(others, ",")
+}
+
+// appendUnique appends the value if it is not blank or already present
+func appendUnique(values []string, value string) []string {
+	if value == "" || stringhelpers.StringArrayIndex(values, value) >= 0 {
+		return values
+	}
+	return append(values, value)
+}
+
+// versionCell returns the version of the deployment along with how its traffic is split if it is routed to several
+// revisions
+func versionCell(d *applications.Deployment) string {
+	if len(d.Traffic) < 2 {
+		return d.Version
+	}
+	var targets []string
+	for _, t := range d.Traffic {
+		name := t.Tag
+		if name == "" {
+			name = t.RevisionName
+		}
+		targets = append(targets, fmt.Sprintf("%s=%d%%", name, t.Percent))
+	}
+	return d.Version + " (" + strings.Join(targets, ",") + ")"
+}
+
+// helmStatusCell returns the highlighted status of a helm release which failed or is pending so that it stands out
+// or blank if it is deployed
+func helmStatusCell(r *applications.HelmRelease) string {
+	if r == nil {
+		return ""
+	}
+	text := fmt.Sprintf("(helm %s rev %d)", r.Status, r.Revision)
+	switch {
+	case r.IsFailed():
+		return termcolor.ColorError(text)
+	case r.IsPending():
+		return termcolor.ColorWarning(text)
+	default:
+		return ""
+	}
+}
+
+// canaryCell returns the phase and canary weight of a rollout such as Progressing 30%
+func canaryCell(r *applications.RolloutStatus) string {
+	if r == nil {
+		return ""
+	}
+	cell := r.Phase
+	if r.Weight > 0 {
+		cell = fmt.Sprintf("%s %d%%", cell, r.Weight)
+	}
+	if r.Step != "" {
+		cell += " step " + r.Step
+	}
+	if r.FailedChecks > 0 {
+		cell = fmt.Sprintf("%s (%d failed checks)", cell, r.FailedChecks)
+	}
+	return strings.TrimSpace(cell)
+}
+
+// hasRollouts returns true if any deployment is managed by a progressive delivery backend so that the CANARY column
+// is shown
+func hasRollouts(list applications.List) bool {
+	for i := range list.Items {
+		for k := range list.Items[i].Environments {
+			for _, d := range list.Items[i].Environments[k].Deployments {
+				if d.Rollout != nil {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// environmentCells returns the cells of an environment without a deployment using the given text for the version
+func (o *ApplicationsOptions) environmentCells(ae *applications.Environment, text string, canary bool) []string {
+	var cells []string
+	if !ae.IsPreview() {
+		cells = append(cells, text)
+	}
+	if canary {
+		cells = append(cells, "")
+	}
+	if !o.HidePod {
+		cells = append(cells, "")
+	}
+	if !o.HideURL {
+		cells = append(cells, "")
+	}
+	return cells
+}
+
 func envTitleName(e v1.Environment) string { //nolint
 	if e.Spec.Kind == v1.EnvironmentKindTypeEdit {
 		return "Edit"
@@ -218,6 +518,28 @@ func envTitleName(e v1.Environment) string { //nolint
 	return e.Name
 }
 
+// repositoryName returns the owner/repository name of the application's source repository
+func repositoryName(a *applications.Application) string {
+	if a.Spec.Org == "" {
+		return a.Spec.Repo
+	}
+	return scm.Join(a.Spec.Org, a.Spec.Repo)
+}
+
+// workloadKinds returns the distinct kinds of workload running the application in any environment
+func workloadKinds(a *applications.Application) string {
+	var kinds []string
+	for k := range a.Environments {
+		for _, d := range a.Environments[k].Deployments {
+			if d.Kind != "" && stringhelpers.StringArrayIndex(kinds, d.Kind) < 0 {
+				kinds = append(kinds, d.Kind)
+			}
+		}
+	}
+	sort.Strings(kinds)
+	return strings.Join(kinds, ",")
+}
+
 func (o *ApplicationsOptions) sortedKeys(envs map[string]v1.Environment) []string {
 	keys := make([]string, 0, len(envs))
 	for k, env := range envs { //nolint
@@ -231,15 +553,22 @@ func (o *ApplicationsOptions) sortedKeys(envs map[string]v1.Environment) []strin
 }
 
 func (o *ApplicationsOptions) generateTableHeaders(list applications.List) table.Table {
-	t := table.CreateTable(os.Stdout)
+	t := table.CreateTable(o.Out)
 	title := "APPLICATION"
 	titles := []string{title}
+	if o.Output == OutputWide {
+		titles = append(titles, "REPOSITORY", "PROVIDER", "KIND")
+	}
 
 	envs := list.Environments()
+	canary := hasRollouts(list)
 
 	for _, k := range o.sortedKeys(envs) {
 		titles = append(titles, strings.ToUpper(envTitleName(envs[k])))
 
+		if canary {
+			titles = append(titles, "CANARY")
+		}
 		if !o.HidePod {
 			titles = append(titles, "PODS")
 		}
@@ -1,11 +1,13 @@
 package get
 
 import (
+	"bytes"
 	"context"
 	"os"
 	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
@@ -13,11 +15,13 @@ import (
 
 	fake2 "github.com/jenkins-x/jx-api/v4/pkg/client/clientset/versioned/fake"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"k8s.io/client-go/kubernetes/fake"
 	"sigs.k8s.io/yaml"
 
 	"github.com/jenkins-x-plugins/jx-application/pkg/applications"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/table"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
 )
 
 func TestGetApplicationsOptions_generateTable(t *testing.T) {
@@ -53,6 +57,225 @@ func TestGetApplicationsOptions_generateTable(t *testing.T) {
 	}
 }
 
+func TestGetApplicationsOptions_generateTableWide(t *testing.T) {
+	list := loadTestApplicationsList(t, "check_application_names")
+	list.Items[3].Environments["staging"].Deployments[0].Kind = applications.KindDeployment
+	list.Items[3].Environments["production"].Deployments[0].Kind = applications.KindStatefulSet
+	list.Items[4].Environments["staging"].Deployments[0].Kind = applications.KindDaemonSet
+	list.Items[4].Environments["staging"].Deployments[0].VersionSource = applications.LabelVersionSource("version")
+	list.Items[4].Environments["staging"].Deployments[0].Image = "ghcr.io/rawlingsj/testapp5:1.0.1"
+	list.Items[5].Environments["staging"].Deployments[0].Image = "ghcr.io/rawlingsj/testapp6:1.0.1"
+	o := &ApplicationsOptions{
+		Output: OutputWide,
+	}
+	got := o.generateTable(list)
+	want := [][]string{
+		{"APPLICATION", "REPOSITORY", "PROVIDER", "KIND", "STAGING", "PODS", "URL", "PRODUCTION", "PODS", "URL"},
+		{"testapp4", "rawlingsj/testapp4", "github", "Deployment,StatefulSet", "1.0.3", "1/1", "http://testapp4-jx-staging.test.nip.io", "1.0.3", "1/1", "http://testapp4-jx-production.test.nip.io"},
+		{"testapp5", "rawlingsj/testapp5", "github", "DaemonSet", "1.0.0 (image 1.0.1)", "1/1", "http://testapp5-jx-staging.test.nip.io", "", "", ""},
+		{"testapp6", "rawlingsj/testapp6", "github", "", "1.0.1", "1/1", "http://testapp6-jx-staging.test.nip.io", "", "", ""},
+	}
+	assert.Equal(t, want, got.Rows)
+}
+
+func TestGetApplicationsOptions_generateTableEnvironmentFailures(t *testing.T) {
+	list := loadTestApplicationsList(t, "check_application_names")
+	production := list.Items[3].Environments["production"]
+	for i := range list.Items[3:] {
+		list.Items[3+i].Environments["production"] = applications.Environment{
+			Environment: production.Environment,
+			FetchStatus: applications.EnvironmentStatusForbidden,
+			FetchError:  "forbidden",
+		}
+	}
+
+	o := &ApplicationsOptions{}
+	got := o.generateTable(list)
+	want := [][]string{
+		{"APPLICATION", "STAGING", "PODS", "URL", "PRODUCTION", "PODS", "URL"},
+		{"testapp4", "1.0.3", "1/1", "http://testapp4-jx-staging.test.nip.io", "<forbidden>", "", ""},
+		{"testapp5", "1.0.0", "1/1", "http://testapp5-jx-staging.test.nip.io", "<forbidden>", "", ""},
+		{"testapp6", "1.0.1", "1/1", "http://testapp6-jx-staging.test.nip.io", "<forbidden>", "", ""},
+	}
+	assert.Equal(t, want, got.Rows)
+}
+
+func TestGetApplicationsOptions_generateTableCanary(t *testing.T) {
+	list := loadTestApplicationsList(t, "check_application_names")
+	list.Items[3].Environments["production"].Deployments[0].Rollout = &applications.RolloutStatus{
+		Backend: applications.BackendFlagger,
+		Phase:   "Progressing",
+		Weight:  30,
+	}
+
+	o := &ApplicationsOptions{HideURL: true}
+	got := o.generateTable(list)
+	want := [][]string{
+		{"APPLICATION", "STAGING", "CANARY", "PODS", "PRODUCTION", "CANARY", "PODS"},
+		{"testapp4", "1.0.3", "", "1/1", "1.0.3", "Progressing 30%", "1/1"},
+		{"testapp5", "1.0.0", "", "1/1", "", "", ""},
+		{"testapp6", "1.0.1", "", "1/1", "", "", ""},
+	}
+	assert.Equal(t, want, got.Rows)
+}
+
+func TestGetApplicationsOptions_generateTableMultipleWorkloads(t *testing.T) {
+	list := loadTestApplicationsList(t, "check_application_names")
+	staging := list.Items[3].Environments["staging"]
+	staging.Deployments = append(staging.Deployments,
+		applications.Deployment{Name: "testapp4", Kind: applications.KindDeployment, Version: "1.0.3", Pods: "2/3"},
+		applications.Deployment{Name: "testapp4", Kind: applications.KindCronJob, Version: "1.0.2", Pods: "1 active"},
+	)
+	list.Items[3].Environments["staging"] = staging
+
+	o := &ApplicationsOptions{}
+	got := o.generateTable(list)
+	assert.Equal(t, []string{"testapp4", "1.0.3,1.0.2", "3/4,1 active", "http://testapp4-jx-staging.test.nip.io", "1.0.3", "1/1", "http://testapp4-jx-production.test.nip.io"}, got.Rows[1])
+}
+
+func TestGetApplicationsOptions_renderOutput(t *testing.T) {
+	list := loadTestApplicationsList(t, "check_application_names")
+
+	t.Run("name", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		o := &ApplicationsOptions{Output: OutputName}
+		o.Out = out
+		require.NoError(t, o.render(list))
+		assert.Equal(t, "testapp4\ntestapp5\ntestapp6\n", out.String())
+	})
+
+	for _, format := range []string{OutputJSON, OutputYAML} {
+		t.Run(format, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			o := &ApplicationsOptions{Output: format, Environment: "staging"}
+			o.Out = out
+			require.NoError(t, o.render(list))
+
+			got := applications.OutputList{}
+			require.NoError(t, yaml.Unmarshal(out.Bytes(), &got))
+			assert.Equal(t, applications.OutputAPIVersion, got.APIVersion)
+			assert.Equal(t, applications.OutputListKind, got.Kind)
+			require.Len(t, got.Items, 3)
+
+			app := got.Items[0]
+			assert.Equal(t, "testapp4", app.Name)
+			assert.Equal(t, "rawlingsj", app.Owner)
+			require.Len(t, app.Environments, 1, "should only include the filtered environment")
+			assert.Equal(t, "staging", app.Environments[0].Name)
+			assert.Equal(t, "jx-staging", app.Environments[0].Namespace)
+			require.Len(t, app.Environments[0].Deployments, 1)
+			assert.Equal(t, "1.0.3", app.Environments[0].Deployments[0].Version)
+		})
+	}
+
+	t.Run("go-template", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		o := &ApplicationsOptions{Output: `go-template={{range orderedEnvironments .}}{{.Name}} {{end}}{{range .Items}}{{if .Environments}}{{.Name}}={{appVersion . "production"}};{{end}}{{end}}`}
+		o.Out = out
+		require.NoError(t, o.validateOutput())
+		require.NoError(t, o.render(list))
+		assert.Equal(t, "staging production testapp4=1.0.3;testapp5=;testapp6=;", out.String())
+	})
+
+	t.Run("go-template-file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "apps.gotmpl")
+		require.NoError(t, os.WriteFile(path, []byte(`{{range .Items}}{{if versionNewer (appVersion . "staging") "1.0.0"}}{{.Name}} {{end}}{{end}}`), 0o600))
+
+		out := &bytes.Buffer{}
+		o := &ApplicationsOptions{Output: "go-template-file=" + path}
+		o.Out = out
+		require.NoError(t, o.render(list))
+		assert.Equal(t, "testapp4 testapp6 ", out.String())
+	})
+
+	t.Run("jsonpath", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		o := &ApplicationsOptions{Output: "jsonpath={.items[*].repository}"}
+		o.Out = out
+		require.NoError(t, o.render(list))
+		assert.Equal(t, "testapp4 testapp5 testapp6", out.String())
+
+		out.Reset()
+		o.Output = `jsonpath={range .items[*]}{.name}={.environments[?(@.name=="staging")].deployments[0].version};{end}`
+		require.NoError(t, o.render(list))
+		assert.Equal(t, "testapp4=1.0.3;testapp5=1.0.0;testapp6=1.0.1;", out.String(), "should use the same schema as the json output")
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, output := range []string{"xml", "json=foo", "jsonpath", "go-template="} {
+			o := &ApplicationsOptions{Output: output}
+			assert.Error(t, o.validateOutput(), output)
+		}
+	})
+}
+
+func TestGetApplicationsOptions_ValidateWatchTimeout(t *testing.T) {
+	_, o := NewCmdGetApplications()
+	o.Watch = true
+	o.Timeout = time.Minute
+	err := o.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--watch")
+}
+
+func TestHighlightChanges(t *testing.T) {
+	previous := [][]string{
+		{"APPLICATION", "STAGING", "PODS"},
+		{"app1", "1.0.0", "1/1"},
+		{"app2", "2.0.0", "1/1"},
+	}
+	rows := [][]string{
+		{"APPLICATION", "STAGING", "PODS"},
+		{"app1", "1.0.1", "1/1"},
+		{"app2", "2.0.0", "1/1"},
+		{"app3", "3.0.0", ""},
+	}
+
+	assert.Equal(t, rows, highlightChanges(rows, nil), "should not highlight the first render")
+
+	got := highlightChanges(rows, previous)
+	assert.Equal(t, rows[0], got[0])
+	assert.Equal(t, []string{"app1", termcolor.ColorWarning("1.0.1"), "1/1"}, got[1])
+	assert.Equal(t, rows[2], got[2])
+	assert.Equal(t, []string{termcolor.ColorWarning("app3"), termcolor.ColorWarning("3.0.0"), ""}, got[3])
+}
+
+func TestGetApplicationsOptions_generateTableHelmStatus(t *testing.T) {
+	list := loadTestApplicationsList(t, "check_application_names")
+	list.Items[3].Environments["production"].Deployments[0].Helm = &applications.HelmRelease{Name: "testapp4", Revision: 3, Status: applications.HelmReleaseStatusFailed}
+	list.Items[4].Environments["staging"].Deployments[0].Helm = &applications.HelmRelease{Name: "testapp5", Revision: 2, Status: "pending-upgrade"}
+	list.Items[5].Environments["staging"].Deployments[0].Helm = &applications.HelmRelease{Name: "testapp6", Revision: 1, Status: applications.HelmReleaseStatusDeployed}
+
+	o := &ApplicationsOptions{HideURL: true, HidePod: true}
+	got := o.generateTable(list)
+	want := [][]string{
+		{"APPLICATION", "STAGING", "PRODUCTION"},
+		{"testapp4", "1.0.3", "1.0.3 " + termcolor.ColorError("(helm failed rev 3)")},
+		{"testapp5", "1.0.0 " + termcolor.ColorWarning("(helm pending-upgrade rev 2)"), ""},
+		{"testapp6", "1.0.1", ""},
+	}
+	assert.Equal(t, want, got.Rows)
+}
+
+func TestVersionCell(t *testing.T) {
+	d := &applications.Deployment{Version: "00002", Traffic: []applications.TrafficTarget{{RevisionName: "myfunc-00002", Percent: 100}}}
+	assert.Equal(t, "00002", versionCell(d))
+
+	d.Traffic = []applications.TrafficTarget{
+		{RevisionName: "myfunc-00002", Percent: 80},
+		{RevisionName: "myfunc-00001", Percent: 20, Tag: "previous"},
+	}
+	assert.Equal(t, "00002 (myfunc-00002=80%,previous=20%)", versionCell(d))
+}
+
+func TestCanaryCell(t *testing.T) {
+	assert.Equal(t, "", canaryCell(nil))
+	assert.Equal(t, "Succeeded", canaryCell(&applications.RolloutStatus{Phase: "Succeeded"}))
+	assert.Equal(t, "Progressing 30%", canaryCell(&applications.RolloutStatus{Phase: "Progressing", Weight: 30}))
+	assert.Equal(t, "Progressing 10% (2 failed checks)", canaryCell(&applications.RolloutStatus{Phase: "Progressing", Weight: 10, FailedChecks: 2}))
+	assert.Equal(t, "Paused 20% step 1/4", canaryCell(&applications.RolloutStatus{Phase: "Paused", Weight: 20, Step: "1/4"}))
+}
+
 // load test ingresses used to find a URL to display in the table
 func loadTestIngresses(t *testing.T, name string, kubeclient *fake.Clientset) {
 	file := filepath.Join("test_data", "generate_table", name, "ingresses.yaml")
@@ -0,0 +1,113 @@
+package get
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jenkins-x-plugins/jx-application/pkg/applications"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/options"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/stringhelpers"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// OutputJSON renders the versioned application list schema as JSON
+	OutputJSON = "json"
+
+	// OutputYAML renders the versioned application list schema as YAML
+	OutputYAML = "yaml"
+
+	// OutputWide renders the table with additional columns
+	OutputWide = "wide"
+
+	// OutputName renders the name of each application on its own line
+	OutputName = "name"
+
+	// OutputGoTemplate renders the go template given after the '=' against the application list
+	OutputGoTemplate = "go-template"
+
+	// OutputGoTemplateFile renders the go template file given after the '=' against the application list
+	OutputGoTemplateFile = "go-template-file"
+
+	// OutputJSONPath renders the JSONPath expression given after the '=' against the application list
+	OutputJSONPath = "jsonpath"
+)
+
+// OutputFormats the supported values of the --output flag
+var OutputFormats = []string{OutputJSON, OutputYAML, OutputWide, OutputName, OutputGoTemplate + "=...", OutputGoTemplateFile + "=...", OutputJSONPath + "=..."}
+
+// outputFormatsWithArgument the output formats which require an argument after the '='
+var outputFormatsWithArgument = []string{OutputGoTemplate, OutputGoTemplateFile, OutputJSONPath}
+
+// splitOutput splits the output flag into the format and its optional argument such as 'jsonpath={.items}'
+func splitOutput(output string) (format, argument string) {
+	idx := strings.Index(output, "=")
+	if idx < 0 {
+		return output, ""
+	}
+	return output[0:idx], output[idx+1:]
+}
+
+func (o *ApplicationsOptions) validateOutput() error {
+	format, argument := splitOutput(o.Output)
+	switch {
+	case o.Output == "", stringhelpers.StringArrayIndex([]string{OutputJSON, OutputYAML, OutputWide, OutputName}, o.Output) >= 0:
+		return nil
+	case stringhelpers.StringArrayIndex(outputFormatsWithArgument, format) >= 0:
+		if argument == "" {
+			return options.InvalidOptionf("output", o.Output, "the %s output format requires a value after the '='", format)
+		}
+		return nil
+	default:
+		return options.InvalidOption("output", o.Output, OutputFormats)
+	}
+}
+
+// isTableOutput returns true if the output is a human readable table
+func (o *ApplicationsOptions) isTableOutput() bool {
+	return o.Output == "" || o.Output == OutputWide
+}
+
+// render writes the applications to the output in the requested format
+func (o *ApplicationsOptions) render(list applications.List) error {
+	format, argument := splitOutput(o.Output)
+	switch format {
+	case OutputJSON:
+		data, err := json.MarshalIndent(list.ToOutput(o.sortedKeys(list.Environments())), "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal applications to JSON: %w", err)
+		}
+		_, err = fmt.Fprintln(o.Out, string(data))
+		return err
+	case OutputYAML:
+		data, err := yaml.Marshal(list.ToOutput(o.sortedKeys(list.Environments())))
+		if err != nil {
+			return fmt.Errorf("failed to marshal applications to YAML: %w", err)
+		}
+		_, err = fmt.Fprint(o.Out, string(data))
+		return err
+	case OutputName:
+		for i := range list.Items {
+			a := &list.Items[i]
+			if len(a.Environments) == 0 {
+				continue
+			}
+			_, err := fmt.Fprintln(o.Out, a.Name())
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case OutputGoTemplate:
+		return o.renderGoTemplate(list, argument, "--output")
+	case OutputGoTemplateFile:
+		return o.renderGoTemplateFile(list, argument)
+	case OutputJSONPath:
+		return o.renderJSONPath(list, argument)
+	default:
+		t := o.generateTable(list)
+		t.Render()
+		return nil
+	}
+}
@@ -0,0 +1,84 @@
+package get
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/jenkins-x-plugins/jx-application/pkg/applications"
+	v1 "github.com/jenkins-x/jx-api/v4/pkg/apis/jenkins.io/v1"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/templater"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// TemplateFuncs returns the helper functions available to go templates
+func TemplateFuncs() template.FuncMap {
+	return template.FuncMap{
+		// orderedEnvironments returns the environments of a list in promotion order
+		"orderedEnvironments": func(l applications.List) []v1.Environment {
+			return l.OrderedEnvironments()
+		},
+		// appVersion returns the version of an application in the given environment
+		"appVersion": func(a applications.Application, envName string) string {
+			env, ok := a.Environments[envName]
+			if !ok || len(env.Deployments) == 0 {
+				return ""
+			}
+			return env.Deployments[0].Version
+		},
+		"compareVersions": applications.CompareVersions,
+		"versionNewer": func(a, b string) bool {
+			return applications.CompareVersions(a, b) > 0
+		},
+		"join":  strings.Join,
+		"upper": strings.ToUpper,
+		"lower": strings.ToLower,
+	}
+}
+
+// renderGoTemplate evaluates the go template against the application list
+func (o *ApplicationsOptions) renderGoTemplate(list applications.List, templateText, path string) error {
+	text, err := templater.Evaluate(TemplateFuncs(), list, templateText, path, "applications")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprint(o.Out, text)
+	return err
+}
+
+// renderGoTemplateFile evaluates the go template in the given file against the application list
+func (o *ApplicationsOptions) renderGoTemplateFile(list applications.List, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read template file %s: %w", path, err)
+	}
+	return o.renderGoTemplate(list, string(data), path)
+}
+
+// renderJSONPath evaluates the JSONPath expression against the same versioned JSON that the json output format prints
+func (o *ApplicationsOptions) renderJSONPath(list applications.List, expression string) error {
+	j := jsonpath.New("applications").AllowMissingKeys(true)
+	err := j.Parse(expression)
+	if err != nil {
+		return fmt.Errorf("failed to parse jsonpath expression %s: %w", expression, err)
+	}
+
+	// lets use the JSON field names rather than the go struct field names
+	data, err := json.Marshal(list.ToOutput(o.sortedKeys(list.Environments())))
+	if err != nil {
+		return fmt.Errorf("failed to marshal applications to JSON: %w", err)
+	}
+	var value interface{}
+	err = json.Unmarshal(data, &value)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal applications JSON: %w", err)
+	}
+
+	err = j.Execute(o.Out, value)
+	if err != nil {
+		return fmt.Errorf("failed to evaluate jsonpath expression %s: %w", expression, err)
+	}
+	return nil
+}
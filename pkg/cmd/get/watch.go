@@ -0,0 +1,122 @@
+package get
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-application/pkg/applications"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/options"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+)
+
+const (
+	// watchDebounce how long to wait for further changes before re-rendering so that a rolling update
+	// does not redraw the table for every pod
+	watchDebounce = 500 * time.Millisecond
+
+	// clearScreen the ANSI escape sequence to move the cursor home and clear the terminal
+	clearScreen = "\033[H\033[2J"
+)
+
+// watch renders the applications then re-renders them whenever they change until the context is cancelled
+func (o *ApplicationsOptions) watch() error {
+	ctx := o.GetContext()
+	g := o.GitClient
+	if g == nil {
+		g = applications.NewContextGitClient(ctx)
+	}
+	w := applications.NewWatcherWithOptions(o.getOptions(), o.RemotePollInterval)
+	w.GitClient = g
+	if o.GitClient == nil {
+		// lets use the git client which is killed when we stop watching
+		fetcher, err := applications.NewReleaseFetcher(o.RemoteFetcher, g, o.RemoteCache)
+		if err != nil {
+			return options.InvalidOption("remote-fetcher", o.RemoteFetcher, applications.Fetchers)
+		}
+		w.ReleaseFetcher = fetcher
+	}
+	err := w.Start(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to watch applications: %w", err)
+	}
+
+	var previous [][]string
+	for {
+		list, err := w.List()
+		if err != nil {
+			return fmt.Errorf("fetching applications: %w", err)
+		}
+		previous, err = o.renderWatch(list, previous)
+		if err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-w.Changes():
+		}
+
+		// lets wait for things to settle down
+		timer := time.NewTimer(watchDebounce)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+	}
+}
+
+// renderWatch renders the latest applications returning the table rows so that changes can be highlighted next time
+func (o *ApplicationsOptions) renderWatch(list applications.List, previous [][]string) ([][]string, error) {
+	if !o.isTableOutput() {
+		return nil, o.render(list)
+	}
+
+	t := o.generateTable(list)
+	rows := t.Rows
+	t.Rows = highlightChanges(rows, previous)
+
+	fmt.Fprint(o.Out, clearScreen)
+	if len(list.Items) == 0 {
+		log.Logger().Infof("No applications found")
+	} else {
+		t.Render()
+	}
+	fmt.Fprintf(o.Out, "\nlast updated %s\n", time.Now().Format(time.TimeOnly))
+	return rows, nil
+}
+
+// highlightChanges returns a copy of the rows with any cell which differs from the previous rows for the same
+// application highlighted. New applications are highlighted in full. The header row is never highlighted
+func highlightChanges(rows, previous [][]string) [][]string {
+	if len(previous) == 0 {
+		return rows
+	}
+	previousRows := map[string][]string{}
+	for _, row := range previous[1:] {
+		if len(row) > 0 {
+			previousRows[row[0]] = row
+		}
+	}
+
+	answer := make([][]string, 0, len(rows))
+	for i, row := range rows {
+		if i == 0 || len(row) == 0 {
+			answer = append(answer, row)
+			continue
+		}
+		old, found := previousRows[row[0]]
+		highlighted := make([]string, 0, len(row))
+		for j, cell := range row {
+			if cell != "" && (!found || j >= len(old) || old[j] != cell) {
+				cell = termcolor.ColorWarning(cell)
+			}
+			highlighted = append(highlighted, cell)
+		}
+		answer = append(answer, highlighted)
+	}
+	return answer
+}
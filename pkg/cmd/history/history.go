@@ -0,0 +1,224 @@
+package history
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-application/pkg/applications"
+	jxc "github.com/jenkins-x/jx-api/v4/pkg/client/clientset/versioned"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/helper"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/templates"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/cli"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/kube"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/kube/jxclient"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/kube/jxenv"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/options"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/table"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// OutputJSON renders the promotion history as JSON
+	OutputJSON = "json"
+
+	// OutputYAML renders the promotion history as YAML
+	OutputYAML = "yaml"
+)
+
+// OutputFormats the supported values of the --output flag
+var OutputFormats = []string{OutputJSON, OutputYAML}
+
+// Options the options for showing the promotion history of an application
+type Options struct {
+	options.BaseOptions
+
+	KubeClient kubernetes.Interface
+	JXClient   jxc.Interface
+
+	Name             string
+	CurrentNamespace string
+	Environment      string
+	Version          string
+	Output           string
+	SkipGit          bool
+	NoCache          bool
+	MaxCommits       int
+	Timeout          time.Duration
+	RemoteCache      *applications.RemoteCache
+	GitClient        gitclient.Interface
+	CommandRunner    cmdrunner.CommandRunner
+}
+
+var (
+	cmdLong = templates.LongDesc(`
+		Shows the promotion history of an application across environments.
+
+		The timeline is reconstructed from the promote steps of the PipelineActivities of the application's repository
+		along with the commits to the environment git repositories which changed the version of the application in
+		their release report. Each promotion shows the version, environment, time, pull request and who merged it.
+
+		Only the most recent changes to each release report are read, see --max-commits. The merged by column shows
+		the author of the commit which changed the release report, which is the author of the pull request rather
+		than the person who merged it when pull requests are squashed or rebased.
+`)
+
+	cmdExample = templates.Examples(`
+		# show the promotion history of an application
+		jx application history myapp
+
+		# when did 1.4.2 reach production?
+		jx application history myapp -e production --version 1.4.2
+
+		# only use the PipelineActivities without cloning the environment git repositories
+		jx application history myapp --skip-git
+	`)
+)
+
+// NewCmdHistory creates the command for showing the promotion history of an application
+func NewCmdHistory() (*cobra.Command, *Options) {
+	o := &Options{}
+	cmd := &cobra.Command{
+		Use:     "history <application>",
+		Short:   "Shows the promotion history of an application across environments",
+		Long:    cmdLong,
+		Example: cmdExample,
+		Args:    cobra.MaximumNArgs(1),
+		Run: func(_ *cobra.Command, args []string) {
+			if len(args) > 0 {
+				o.Name = args[0]
+			}
+			err := o.Run()
+			helper.CheckErr(err)
+		},
+	}
+	cmd.Flags().StringVarP(&o.Environment, "env", "e", "", "Only show the promotions to the given environment")
+	cmd.Flags().StringVarP(&o.Version, "version", "", "", "Only show the promotions of the given version")
+	cmd.Flags().StringVarP(&o.Output, "output", "o", "", "The output format. One of: "+strings.Join(OutputFormats, "|"))
+	cmd.Flags().BoolVarP(&o.SkipGit, "skip-git", "", false, "Do not read the history of the environment git repositories")
+	cmd.Flags().BoolVarP(&o.NoCache, "no-cache", "", false, "Clone the environment git repositories into temporary directories rather than reusing cached clones")
+	cmd.Flags().IntVarP(&o.MaxCommits, "max-commits", "", applications.DefaultHistoryMaxCommits, "The maximum number of the most recent changes to the release report of each environment git repository to read")
+	cmd.Flags().DurationVarP(&o.Timeout, "timeout", "", 0, "The maximum time to spend finding the history. Zero means no timeout")
+
+	o.BaseOptions.AddBaseFlags(cmd)
+	return cmd, o
+}
+
+// Validate verifies settings
+func (o *Options) Validate() error {
+	if o.Name == "" {
+		return options.MissingOption("application")
+	}
+	if o.Output != "" && o.Output != OutputJSON && o.Output != OutputYAML {
+		return options.InvalidOption("output", o.Output, OutputFormats)
+	}
+	var err error
+	if o.JXClient == nil {
+		o.JXClient, o.CurrentNamespace, err = jxclient.LazyCreateJXClientAndNamespace(o.JXClient, o.CurrentNamespace)
+		if err != nil {
+			return fmt.Errorf("failed to create jx client: %w", err)
+		}
+	}
+	if o.KubeClient == nil {
+		o.KubeClient, err = kube.LazyCreateKubeClient(o.KubeClient)
+		if err != nil {
+			return fmt.Errorf("failed to create kube client: %w", err)
+		}
+	}
+	ns, _, err := jxenv.GetDevNamespace(o.KubeClient, o.CurrentNamespace)
+	if err != nil {
+		return fmt.Errorf("failed to find dev namespace: %w", err)
+	}
+	if ns != "" {
+		o.CurrentNamespace = ns
+	}
+	if o.RemoteCache == nil && !o.NoCache && !o.SkipGit {
+		dir, err := applications.DefaultRemoteCacheDir()
+		if err != nil {
+			return err
+		}
+		o.RemoteCache = &applications.RemoteCache{Dir: dir}
+	}
+	if o.GitClient == nil && o.CommandRunner != nil {
+		o.GitClient = cli.NewCLIClient("", o.CommandRunner)
+	}
+	if o.Out == nil {
+		o.Out = os.Stdout
+	}
+	return nil
+}
+
+// Run implements this command
+func (o *Options) Run() error {
+	err := o.Validate()
+	if err != nil {
+		return fmt.Errorf("failed to validate: %w", err)
+	}
+	err = o.BaseOptions.Validate()
+	if err != nil {
+		return fmt.Errorf("failed to validate: %w", err)
+	}
+
+	ctx := o.GetContext()
+	if o.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.Timeout)
+		defer cancel()
+	}
+	historyOptions := &applications.HistoryOptions{
+		JXClient:    o.JXClient,
+		GitClient:   o.GitClient,
+		Namespace:   o.CurrentNamespace,
+		Version:     o.Version,
+		SkipGit:     o.SkipGit,
+		RemoteCache: o.RemoteCache,
+		MaxCommits:  o.MaxCommits,
+	}
+	if o.Environment != "" {
+		historyOptions.Environments = []string{o.Environment}
+	}
+	h, err := historyOptions.History(ctx, o.Name)
+	if err != nil {
+		return fmt.Errorf("failed to find the history of application %s: %w", o.Name, err)
+	}
+	return o.render(h)
+}
+
+func (o *Options) render(h *applications.History) error {
+	switch o.Output {
+	case OutputJSON:
+		data, err := json.MarshalIndent(h, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal history to JSON: %w", err)
+		}
+		_, err = fmt.Fprintln(o.Out, string(data))
+		return err
+	case OutputYAML:
+		data, err := yaml.Marshal(h)
+		if err != nil {
+			return fmt.Errorf("failed to marshal history to YAML: %w", err)
+		}
+		_, err = fmt.Fprint(o.Out, string(data))
+		return err
+	default:
+		t := table.CreateTable(o.Out)
+		t.AddRow("VERSION", "ENVIRONMENT", "STATUS", "TIMESTAMP", "PULL REQUEST", "MERGED BY")
+		for i := range h.Entries {
+			e := &h.Entries[i]
+			timestamp := ""
+			if e.Timestamp != nil {
+				timestamp = e.Timestamp.UTC().Format(time.RFC3339)
+			}
+			t.AddRow(e.Version, e.Environment, e.Status, timestamp, e.PullRequestURL, e.MergedBy)
+		}
+		t.Render()
+		return nil
+	}
+}
@@ -0,0 +1,67 @@
+package history
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "github.com/jenkins-x/jx-api/v4/pkg/apis/jenkins.io/v1"
+	fakejx "github.com/jenkins-x/jx-api/v4/pkg/client/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestHistoryTable(t *testing.T) {
+	ns := "jx"
+	merged := metav1.NewTime(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC))
+	jxClient := fakejx.NewSimpleClientset(
+		&v1.SourceRepository{
+			ObjectMeta: metav1.ObjectMeta{Name: "myorg-myapp", Namespace: ns},
+			Spec:       v1.SourceRepositorySpec{Org: "myorg", Repo: "myapp"},
+		},
+		&v1.Environment{
+			ObjectMeta: metav1.ObjectMeta{Name: "production", Namespace: ns},
+			Spec:       v1.EnvironmentSpec{Namespace: "jx-production", Kind: v1.EnvironmentKindTypePermanent},
+		},
+		&v1.PipelineActivity{
+			ObjectMeta: metav1.ObjectMeta{Name: "myorg-myapp-master-3", Namespace: ns},
+			Spec: v1.PipelineActivitySpec{
+				GitOwner:      "myorg",
+				GitRepository: "myapp",
+				Version:       "1.4.2",
+				Steps: []v1.PipelineActivityStep{{
+					Kind: v1.ActivityStepKindTypePromote,
+					Promote: &v1.PromoteActivityStep{
+						CoreActivityStep: v1.CoreActivityStep{Status: v1.ActivityStatusTypeSucceeded},
+						Environment:      "production",
+						PullRequest: &v1.PromotePullRequestStep{
+							CoreActivityStep: v1.CoreActivityStep{CompletedTimestamp: &merged},
+							PullRequestURL:   "https://github.com/myorg/environment-production/pull/7",
+						},
+					},
+				}},
+			},
+		},
+	)
+
+	_, o := NewCmdHistory()
+	o.Name = "myapp"
+	o.SkipGit = true
+	o.JXClient = jxClient
+	o.KubeClient = fake.NewSimpleClientset(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: ns}})
+	o.CurrentNamespace = ns
+	o.Ctx = context.TODO()
+	out := &bytes.Buffer{}
+	o.Out = out
+	require.NoError(t, o.Run())
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Equal(t, []string{"VERSION", "ENVIRONMENT", "STATUS", "TIMESTAMP", "PULL", "REQUEST", "MERGED", "BY"}, strings.Fields(lines[0]))
+	assert.Equal(t, []string{"1.4.2", "production", "Succeeded", "2024-05-01T10:00:00Z", "https://github.com/myorg/environment-production/pull/7"}, strings.Fields(lines[1]))
+}
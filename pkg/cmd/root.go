@@ -2,7 +2,10 @@ package cmd
 
 import (
 	"github.com/jenkins-x-plugins/jx-application/pkg/cmd/deletecmd"
+	"github.com/jenkins-x-plugins/jx-application/pkg/cmd/describe"
+	"github.com/jenkins-x-plugins/jx-application/pkg/cmd/diff"
 	"github.com/jenkins-x-plugins/jx-application/pkg/cmd/get"
+	"github.com/jenkins-x-plugins/jx-application/pkg/cmd/history"
 	"github.com/jenkins-x-plugins/jx-application/pkg/common"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/options"
 	"github.com/jenkins-x/jx-logging/v3/pkg/log"
@@ -34,7 +37,10 @@ func Main() *cobra.Command {
 	o := options.BaseOptions{}
 	o.AddBaseFlags(cmd)
 	cmd.AddCommand(cobras.SplitCommand(deletecmd.NewCmdDelete()))
+	cmd.AddCommand(cobras.SplitCommand(describe.NewCmdDescribe()))
+	cmd.AddCommand(cobras.SplitCommand(diff.NewCmdDiff()))
 	cmd.AddCommand(cobras.SplitCommand(get.NewCmdGetApplications()))
+	cmd.AddCommand(cobras.SplitCommand(history.NewCmdHistory()))
 	cmd.AddCommand(cobras.SplitCommand(version.NewCmdVersion()))
 	return cmd
 }